@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	CsvPath           string   `long:"csv" description:"CSVファイルのパス(1列目のICCIDのみ参照)"`
+	ICCID             []string `long:"iccid" description:"対象のSIMのICCID(複数指定可)"`
+	AccessToken       string   `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string   `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Zone              string   `long:"zone" description:"さくらのクラウドゾーン(モバイルゲートウェイから対象を選ぶ場合)"`
+	MgwResourceID     string   `long:"mgw-resource-id" description:"モバイルゲートウェイのリソースID(モバイルゲートウェイから対象を選ぶ場合)"`
+	CIDR              []string `long:"cidr" description:"モバイルゲートウェイから対象を選ぶ場合に、IPアドレスの範囲で絞り込む(複数指定可)"`
+	Deactivate        bool     `long:"deactivate" description:"有効化の代わりに無効化する"`
+}
+
+// コマンドライン引数のバリデーションを行い、絞り込みに使う CIDR を返す
+func validateArgs(opts Options) ([]*net.IPNet, error) {
+	fromList := opts.CsvPath != "" || len(opts.ICCID) > 0
+	fromMgw := opts.MgwResourceID != ""
+	if fromList == fromMgw {
+		return nil, errors.New("コマンドライン引数にCSVファイルのパスかICCID、またはモバイルゲートウェイのリソースIDのいずれかを指定してください")
+	}
+
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return nil, errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	if !fromMgw {
+		if len(opts.CIDR) > 0 {
+			return nil, errors.New("CIDRによる絞り込みはモバイルゲートウェイのリソースIDを指定した場合のみ利用できます")
+		}
+		return nil, nil
+	}
+
+	err := common.ValidateZone(opts.Zone)
+	if err != nil {
+		return nil, err
+	}
+
+	ipNets := make([]*net.IPNet, 0, len(opts.CIDR))
+	for _, cidr := range opts.CIDR {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("正しいフォーマットのCIDRを指定してください: %s", err.Error())
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, nil
+}
+
+// 対象の SIM を取得する
+func loadTargetSims(opts Options, ipNets []*net.IPNet) ([]common.MgwSim, error) {
+	if opts.MgwResourceID != "" {
+		// モバイルゲートウェイ配下の SIM から選ぶ
+		sims, err := common.GetSimsInMGW(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwResourceID)
+		if err != nil {
+			return nil, err
+		}
+		if len(ipNets) > 0 {
+			sims = common.FilterSimsByCIDRs(ipNets, sims)
+		}
+		return sims, nil
+	}
+
+	// CSVファイル、コマンドライン引数の ICCID からアカウント内の SIM を探す
+	iccids, err := common.LoadICCIDList(opts.CsvPath, opts.ICCID)
+	if err != nil {
+		return nil, err
+	}
+	return common.FindSimsByICCID(opts.AccessToken, opts.AccessTokenSecret, iccids)
+}
+
+func main() {
+	// コマンドライン引数の確認
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	ipNets, err := validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// 対象のSIMの取得
+	fmt.Printf("対象のSIMの取得中...")
+	sims, err := loadTargetSims(opts, ipNets)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+
+	label := "SIM一括有効化"
+	if opts.Deactivate {
+		label = "SIM一括無効化"
+	}
+	fmt.Printf("%s 開始\n", label)
+	err = common.SetSimActivationFromList(opts.AccessToken, opts.AccessTokenSecret, sims, !opts.Deactivate)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("%s 完了\n", label)
+
+	os.Exit(0)
+}
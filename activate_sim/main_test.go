@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+func TestMatchSimsByICCID(t *testing.T) {
+	t.Run("ICCIDの順にSIMを返し、見つからないICCIDも返す", func(t *testing.T) {
+		accountSims := make([]common.AccountSim, 2)
+		accountSims[0].ID = "113000000000"
+		accountSims[0].Status.ICCID = "8981040000000123400"
+		accountSims[0].Status.Sim.Activated = true
+		accountSims[1].ID = "113000000001"
+		accountSims[1].Status.ICCID = "8981040000000123401"
+
+		found, notFound := common.MatchSimsByICCID(accountSims, []string{"8981040000000123401", "8981040000000123400", "8981040000000123499"})
+
+		expected := []common.MgwSim{
+			{ICCID: "8981040000000123401", ResourceID: "113000000001", Activated: false},
+			{ICCID: "8981040000000123400", ResourceID: "113000000000", Activated: true},
+		}
+		if !reflect.DeepEqual(expected, found) {
+			t.Fatalf("sims expected...%v, got ...%v\n", expected, found)
+		}
+		if !reflect.DeepEqual([]string{"8981040000000123499"}, notFound) {
+			t.Fatalf("unexpected not found iccids...%v\n", notFound)
+		}
+		t.Log("OK")
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("CSVファイルのパスとモバイルゲートウェイのリソースIDの両方を指定するとエラーになる", func(t *testing.T) {
+		options := Options{CsvPath: "testdata/load_test.csv", AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", MgwResourceID: "aaaaaaa"}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("対象のSIMを指定しないとエラーになる", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret"}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("CSVファイルを指定した場合はゾーンが無くてもエラーにならない", func(t *testing.T) {
+		options := Options{CsvPath: "testdata/load_test.csv", AccessToken: "Token", AccessTokenSecret: "Secret"}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("CSVファイルを指定した場合はCIDRで絞り込めない", func(t *testing.T) {
+		options := Options{CsvPath: "testdata/load_test.csv", AccessToken: "Token", AccessTokenSecret: "Secret", CIDR: []string{"192.168.1.0/24"}}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("モバイルゲートウェイから選ぶ場合は不正なゾーンを入力したら、エラーが返る", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "tk3a", MgwResourceID: "aaaaaaa"}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
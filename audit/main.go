@@ -0,0 +1,550 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	AccessToken       string   `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string   `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	CIDR              []string `long:"cidr" description:"SIMに割り当てるIPアドレスのCIDR(複数指定可)"`
+	Output            string   `long:"output" default:"table" description:"出力形式(table, json)"`
+	Fix               bool     `long:"fix" description:"モバイルゲートウェイへの追加とIPアドレスの設定を補完する"`
+	Zone              string   `long:"zone" description:"未登録のSIMを追加するモバイルゲートウェイのゾーン(--fix 指定時)"`
+	MgwResourceID     string   `long:"mgw-resource-id" description:"未登録のSIMを追加するモバイルゲートウェイのリソースID(--fix 指定時)"`
+	MgwName           string   `long:"mgw-name" description:"未登録のSIMを追加するモバイルゲートウェイの名前(リソースIDの代わりに指定)"`
+	CsvPath           string   `long:"csv" description:"修正対象のSIMのCSVファイルのパス(1列目のICCIDのみ参照, --fix 指定時)"`
+	ICCID             []string `long:"iccid" description:"修正対象のSIMのICCID(複数指定可, --fix 指定時)"`
+	Yes               bool     `long:"yes" description:"確認せずに修正する"`
+	DryRun            bool     `long:"dry-run" description:"APIを呼び出さずに修正内容のみ表示する"`
+}
+
+// 検出する不整合の種類
+const (
+	KindUnattached  = "unattached"
+	KindNoIP        = "no_ip"
+	KindOutsideCIDR = "outside_cidr"
+	KindDuplicateIP = "duplicate_ip"
+	KindInactive    = "inactive"
+)
+
+// 不整合の種類の表示順
+var kindOrder = []string{KindUnattached, KindNoIP, KindOutsideCIDR, KindDuplicateIP, KindInactive}
+
+// モバイルゲートウェイに登録されている SIM
+type AttachedSim struct {
+	Zone  string
+	MgwID string
+	Sim   common.MgwSim
+}
+
+// 検出した不整合
+type Finding struct {
+	Kind          string `json:"kind"`
+	ICCID         string `json:"iccid"`
+	ResourceID    string `json:"resource_id"`
+	Zone          string `json:"zone"`
+	MgwResourceID string `json:"mgw_resource_id"`
+	IP            string `json:"ip"`
+	Detail        string `json:"detail"`
+}
+
+// IP アドレスを割り当てるモバイルゲートウェイ
+type TargetMgw struct {
+	Zone  string
+	MgwID string
+}
+
+// 不整合の修正内容
+type FixEntry struct {
+	ICCID      string
+	ResourceID string
+	Zone       string
+	MgwID      string
+	// モバイルゲートウェイへの追加が必要か
+	Attach bool
+	IP     string
+}
+
+// コマンドライン引数のバリデーションを行う
+func validateArgs(opts Options) ([]*net.IPNet, error) {
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return nil, errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	err := common.ValidateOutput(opts.Output, []string{"table", "json"})
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MgwResourceID != "" && opts.MgwName != "" {
+		return nil, errors.New("モバイルゲートウェイのリソースIDと名前は同時に指定できません")
+	}
+	if opts.MgwResourceID != "" || opts.MgwName != "" {
+		if !opts.Fix {
+			return nil, errors.New("モバイルゲートウェイは --fix と一緒に指定してください")
+		}
+		err = common.ValidateZone(opts.Zone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !opts.Fix && (opts.CsvPath != "" || len(opts.ICCID) > 0 || opts.Yes || opts.DryRun) {
+		return nil, errors.New("--csv, --iccid, --yes, --dry-run は --fix と一緒に指定してください")
+	}
+
+	if opts.Fix && len(opts.CIDR) == 0 {
+		return nil, errors.New("--fix を指定する場合は、SIMに割り当てるIPアドレスのCIDRを指定してください")
+	}
+
+	ipNets := make([]*net.IPNet, 0, len(opts.CIDR))
+	for _, cidr := range opts.CIDR {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("正しいフォーマットのCIDRを指定してください: %s", err.Error())
+		}
+		err = common.ValidateSimCIDR(ipNet)
+		if err != nil {
+			return nil, err
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+
+	err = common.CheckOverlappingCIDRs(ipNets)
+	if err != nil {
+		return nil, err
+	}
+
+	return ipNets, nil
+}
+
+// auditSims
+// アカウント内の SIM とモバイルゲートウェイに登録されている SIM を突き合わせて不整合を検出する
+// ipNets が空の場合は CIDR の範囲外の検出を行わない
+func auditSims(accountSims []common.AccountSim, attached []AttachedSim, ipNets []*net.IPNet) []Finding {
+	findings := make([]Finding, 0)
+
+	// IP アドレスはモバイルゲートウェイごとに独立しているので、ゾーンとモバイルゲートウェイも含めて重複を調べる
+	attachedByICCID := make(map[string]AttachedSim, len(attached))
+	iccidsByIP := make(map[string][]string)
+	for _, a := range attached {
+		attachedByICCID[a.Sim.ICCID] = a
+		if a.Sim.IP != "" {
+			key := a.Zone + "/" + a.MgwID + "/" + a.Sim.IP
+			iccidsByIP[key] = append(iccidsByIP[key], a.Sim.ICCID)
+		}
+	}
+
+	for _, accountSim := range accountSims {
+		sim := accountSim.SimInfo()
+		a, exists := attachedByICCID[sim.ICCID]
+		if !exists {
+			findings = append(findings, Finding{
+				Kind:       KindUnattached,
+				ICCID:      sim.ICCID,
+				ResourceID: sim.ResourceID,
+				Detail:     "モバイルゲートウェイに登録されていません",
+			})
+		} else {
+			// モバイルゲートウェイ配下の SIM の情報の方が新しい
+			sim = a.Sim
+		}
+		if !sim.Activated {
+			findings = append(findings, Finding{
+				Kind:          KindInactive,
+				ICCID:         sim.ICCID,
+				ResourceID:    sim.ResourceID,
+				Zone:          a.Zone,
+				MgwResourceID: a.MgwID,
+				IP:            sim.IP,
+				Detail:        "SIMが有効化されていません",
+			})
+		}
+	}
+
+	for _, a := range attached {
+		finding := Finding{
+			ICCID:         a.Sim.ICCID,
+			ResourceID:    a.Sim.ResourceID,
+			Zone:          a.Zone,
+			MgwResourceID: a.MgwID,
+			IP:            a.Sim.IP,
+		}
+		if a.Sim.IP == "" {
+			finding.Kind = KindNoIP
+			finding.Detail = "IPアドレスが設定されていません"
+			findings = append(findings, finding)
+			continue
+		}
+		if len(ipNets) > 0 && len(common.FilterSimsByCIDRs(ipNets, []common.MgwSim{a.Sim})) == 0 {
+			finding.Kind = KindOutsideCIDR
+			finding.Detail = "IPアドレスが指定されたCIDRの範囲外です"
+			findings = append(findings, finding)
+		}
+		if iccids := iccidsByIP[a.Zone+"/"+a.MgwID+"/"+a.Sim.IP]; len(iccids) > 1 {
+			others := slices.DeleteFunc(slices.Clone(iccids), func(iccid string) bool { return iccid == a.Sim.ICCID })
+			finding.Kind = KindDuplicateIP
+			finding.Detail = fmt.Sprintf("IPアドレスが同じモバイルゲートウェイの他のSIMと重複しています(ICCID: %s)", strings.Join(others, ", "))
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		ki := slices.Index(kindOrder, findings[i].Kind)
+		kj := slices.Index(kindOrder, findings[j].Kind)
+		if ki != kj {
+			return ki < kj
+		}
+		return findings[i].ICCID < findings[j].ICCID
+	})
+	return findings
+}
+
+// targetMgws
+// 修正で IP アドレスを割り当てるモバイルゲートウェイの一覧を返す
+func targetMgws(findings []Finding, zone string, mgwID string) []TargetMgw {
+	targets := make([]TargetMgw, 0)
+	for _, finding := range findings {
+		var target TargetMgw
+		switch finding.Kind {
+		case KindUnattached:
+			if mgwID == "" {
+				continue
+			}
+			target = TargetMgw{Zone: zone, MgwID: mgwID}
+		case KindNoIP:
+			target = TargetMgw{Zone: finding.Zone, MgwID: finding.MgwResourceID}
+		default:
+			continue
+		}
+		if !slices.Contains(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// filterFindings
+// 修正対象の ICCID の不整合だけを返す
+// iccids が空の場合はすべての不整合を返す
+func filterFindings(findings []Finding, iccids []string) []Finding {
+	if len(iccids) == 0 {
+		return findings
+	}
+	filtered := make([]Finding, 0, len(findings))
+	for _, finding := range findings {
+		if slices.Contains(iccids, finding.ICCID) {
+			filtered = append(filtered, finding)
+		}
+	}
+	return filtered
+}
+
+// planFixes
+// 未登録の SIM と IP アドレスが無い SIM の修正内容を作成する
+// 未登録の SIM は指定されたモバイルゲートウェイに追加し(mgwID が空の場合は修正しない)、
+// IP アドレスはモバイルゲートウェイごとに ipNets 内の未使用の IP アドレスを順に割り当てる
+// interfaceNets には "ゾーン/リソースID" ごとのモバイルゲートウェイのインタフェースのネットワークを渡し、
+// その範囲の IP アドレスは割り当てない
+func planFixes(findings []Finding, attached []AttachedSim, zone string, mgwID string, ipNets []*net.IPNet, interfaceNets map[string][]*net.IPNet) ([]FixEntry, error) {
+	entries := make([]FixEntry, 0)
+	for _, finding := range findings {
+		switch finding.Kind {
+		case KindUnattached:
+			if mgwID == "" {
+				// 追加先が無いので修正しない
+				continue
+			}
+			entries = append(entries, FixEntry{ICCID: finding.ICCID, ResourceID: finding.ResourceID, Zone: zone, MgwID: mgwID, Attach: true})
+		case KindNoIP:
+			entries = append(entries, FixEntry{ICCID: finding.ICCID, ResourceID: finding.ResourceID, Zone: finding.Zone, MgwID: finding.MgwResourceID})
+		}
+	}
+
+	// モバイルゲートウェイごとに使用中の IP アドレスを集める
+	usedByMgw := make(map[string]map[string]struct{})
+	for _, a := range attached {
+		key := a.Zone + "/" + a.MgwID
+		if usedByMgw[key] == nil {
+			usedByMgw[key] = make(map[string]struct{})
+		}
+		if a.Sim.IP != "" {
+			usedByMgw[key][a.Sim.IP] = struct{}{}
+		}
+	}
+
+	availableByMgw := make(map[string][]string)
+	for i := range entries {
+		key := entries[i].Zone + "/" + entries[i].MgwID
+		available, exists := availableByMgw[key]
+		if !exists {
+			// 指定されたCIDRの順に埋めていく
+			available = make([]string, 0)
+			for _, ipNet := range ipNets {
+				for ipaddr := range common.GetAvailableIPAddresses(ipNet.IP, ipNet, usedByMgw[key]) {
+					ip := net.ParseIP(ipaddr)
+					if slices.ContainsFunc(interfaceNets[key], func(interfaceNet *net.IPNet) bool { return interfaceNet.Contains(ip) }) {
+						continue
+					}
+					available = append(available, ipaddr)
+				}
+			}
+		}
+		if len(available) == 0 {
+			return nil, fmt.Errorf("モバイルゲートウェイ(%s)に割り当て可能なIPアドレスがありません(ICCID: %s)", entries[i].MgwID, entries[i].ICCID)
+		}
+		entries[i].IP = available[0]
+		availableByMgw[key] = available[1:]
+	}
+	return entries, nil
+}
+
+// 修正内容を表示する
+func writeFixPlan(w io.Writer, entries []FixEntry) {
+	fmt.Fprintf(w, "以下のSIM %d 枚を修正します\n", len(entries))
+	for _, entry := range entries {
+		steps := make([]string, 0, 2)
+		if entry.Attach {
+			steps = append(steps, fmt.Sprintf("モバイルゲートウェイ(%s)に追加", entry.MgwID))
+		}
+		steps = append(steps, fmt.Sprintf("IPアドレスを設定(%s)", entry.IP))
+		fmt.Fprintf(w, "  ICCID: %s, リソースID: %s: %s\n", entry.ICCID, entry.ResourceID, strings.Join(steps, ", "))
+	}
+}
+
+// confirm
+// 修正を実行してよいか確認する。yes と入力された場合のみ true を返す
+func confirm(r io.Reader, w io.Writer) bool {
+	fmt.Fprint(w, "修正を実行しますか? (yes/no): ")
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false
+	}
+	return strings.TrimSpace(answer) == "yes"
+}
+
+// fixSims
+// 修正内容に従って SIM をモバイルゲートウェイに追加し、IP アドレスを設定する
+// 標準出力の不整合の一覧をパイプで渡せるように、経過は w(標準エラー出力)に出す
+func fixSims(w io.Writer, accessToken string, accessTokenSecret string, entries []FixEntry) error {
+	for _, entry := range entries {
+		fmt.Fprintf(w, "修正(ICCID: %s)", entry.ICCID)
+
+		// MGWにSIMを登録
+		if entry.Attach {
+			fmt.Fprintf(w, ", モバイルゲートウェイに追加(%s)", entry.MgwID)
+			err := common.AssignSimToMgw(accessToken, accessTokenSecret, entry.Zone, entry.MgwID, entry.ResourceID)
+			if err != nil {
+				fmt.Fprintf(w, "[FAILED]\n")
+				return err
+			}
+			fmt.Fprintf(w, "[OK]")
+		}
+
+		// SIMにIPアドレスを設定
+		fmt.Fprintf(w, ", IPアドレスを設定(%s)", entry.IP)
+		err := common.AssignIPAddressToSim(accessToken, accessTokenSecret, entry.ResourceID, entry.IP)
+		if err != nil {
+			fmt.Fprintf(w, "[FAILED]\n")
+			return err
+		}
+		fmt.Fprintf(w, "[OK]\n")
+	}
+	return nil
+}
+
+// 検出した不整合を指定された形式で出力する
+func writeFindings(w io.Writer, output string, findings []Finding) error {
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(findings)
+	default:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, err := fmt.Fprintln(writer, "KIND\tICCID\tRESOURCE_ID\tZONE\tMGW_RESOURCE_ID\tIP\tDETAIL")
+		if err != nil {
+			return err
+		}
+		for _, finding := range findings {
+			_, err = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				finding.Kind, finding.ICCID, finding.ResourceID, finding.Zone, finding.MgwResourceID, finding.IP, finding.Detail)
+			if err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+	}
+}
+
+// すべてのゾーンのモバイルゲートウェイに登録されている SIM を取得する
+func getAttachedSims(accessToken string, accessTokenSecret string) ([]AttachedSim, error) {
+	attached := make([]AttachedSim, 0)
+	for _, zone := range common.ValidZones {
+		mgws, err := common.GetMgwsInZone(accessToken, accessTokenSecret, zone)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", zone, err.Error())
+		}
+		for _, mgw := range mgws {
+			sims, err := common.GetSimsInMGW(accessToken, accessTokenSecret, zone, mgw.ID)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", zone, err.Error())
+			}
+			for _, sim := range sims {
+				attached = append(attached, AttachedSim{Zone: zone, MgwID: mgw.ID, Sim: sim})
+			}
+		}
+	}
+	return attached, nil
+}
+
+func main() {
+	// コマンドラインオプションのパース
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// コマンドライン引数を バリデーションする
+	ipNets, err := validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// 結果をパイプで渡せるように、標準エラー出力に出す
+	fmt.Fprintln(os.Stderr, "情報を取得しています...")
+
+	accountSims, err := common.GetSimsInAccount(opts.AccessToken, opts.AccessTokenSecret)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	attached, err := getAttachedSims(opts.AccessToken, opts.AccessTokenSecret)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// 不整合を表示する
+	findings := auditSims(accountSims, attached, ipNets)
+	err = writeFindings(os.Stdout, opts.Output, findings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "結果の出力に失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if !opts.Fix {
+		os.Exit(0)
+	}
+
+	// 以降の経過は、不整合の一覧をパイプで渡せるように標準エラー出力に出す
+
+	// 修正対象のSIMを絞り込む
+	if opts.CsvPath != "" || len(opts.ICCID) > 0 {
+		iccids, err := common.LoadICCIDList(opts.CsvPath, opts.ICCID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(1)
+		}
+		findings = filterFindings(findings, iccids)
+	}
+
+	// 名前が指定されていればMGWのリソースIDを取得
+	if opts.MgwName != "" {
+		opts.MgwResourceID, err = common.ResolveMgwID(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(1)
+		}
+	}
+	if opts.MgwResourceID == "" {
+		unattached := 0
+		for _, finding := range findings {
+			if finding.Kind == KindUnattached {
+				unattached++
+			}
+		}
+		if unattached > 0 {
+			fmt.Fprintf(os.Stderr, "警告: 追加先のモバイルゲートウェイが指定されていないため、モバイルゲートウェイに登録されていないSIM %d 枚は修正しません\n", unattached)
+		}
+	}
+
+	// CIDRがIPアドレスを割り当てるMGWのインタフェースのネットワークと重複していないか確認
+	fmt.Fprintf(os.Stderr, "モバイルゲートウェイのインタフェースの確認中...")
+	interfaceNets := make(map[string][]*net.IPNet)
+	warnings := make([]string, 0)
+	for _, target := range targetMgws(findings, opts.Zone, opts.MgwResourceID) {
+		nets, err := common.GetMgwInterfaceNetworks(opts.AccessToken, opts.AccessTokenSecret, target.Zone, target.MgwID)
+		if err != nil {
+			// エラーメッセージを出力
+			fmt.Fprintln(os.Stderr, "[NG]")
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(1)
+		}
+		interfaceNets[target.Zone+"/"+target.MgwID] = nets
+		for _, warning := range common.GetInterfaceOverlapWarnings(ipNets, nets) {
+			warnings = append(warnings, fmt.Sprintf("%s(モバイルゲートウェイ: %s)", warning, target.MgwID))
+		}
+	}
+	fmt.Fprintln(os.Stderr, "[OK]")
+	for _, warning := range warnings {
+		// 重複する範囲のIPアドレスは割り当てずに修正を続ける
+		fmt.Fprintf(os.Stderr, "警告: %s\n", warning)
+	}
+
+	// 修正内容の作成
+	fmt.Fprintf(os.Stderr, "修正内容の作成中...")
+	entries, err := planFixes(findings, attached, opts.Zone, opts.MgwResourceID, ipNets, interfaceNets)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Fprintln(os.Stderr, "[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "[OK]")
+
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "修正するSIMはありません")
+		os.Exit(0)
+	}
+
+	// 修正内容を表示し、ドライランでなければ確認する
+	writeFixPlan(os.Stderr, entries)
+	if opts.DryRun {
+		os.Exit(0)
+	}
+	if !opts.Yes && !confirm(os.Stdin, os.Stderr) {
+		fmt.Fprintln(os.Stderr, "修正を中止しました")
+		os.Exit(1)
+	}
+
+	fmt.Fprintln(os.Stderr, "不整合の修正 開始")
+	err = fixSims(os.Stderr, opts.AccessToken, opts.AccessTokenSecret, entries)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "不整合の修正 完了")
+
+	os.Exit(0)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// テストに使用するアカウント内の SIM を作成する
+func newAccountSim(iccid string, resourceID string, activated bool) common.AccountSim {
+	sim := common.AccountSim{ID: resourceID}
+	sim.Status.ICCID = iccid
+	sim.Status.Sim.Activated = activated
+	return sim
+}
+
+// テストに使用する SIM
+var testAccountSims = []common.AccountSim{
+	newAccountSim("8981040000000123400", "290000000000", true),
+	newAccountSim("8981040000000123401", "290000000001", true),
+	newAccountSim("8981040000000123402", "290000000002", true),
+	newAccountSim("8981040000000123403", "290000000003", true),
+	newAccountSim("8981040000000123404", "290000000004", false),
+}
+
+var testAttachedSims = []AttachedSim{
+	{Zone: "is1b", MgwID: "113000000000", Sim: common.MgwSim{ICCID: "8981040000000123400", ResourceID: "290000000000", IP: "192.168.1.1", Activated: true}},
+	{Zone: "is1b", MgwID: "113000000000", Sim: common.MgwSim{ICCID: "8981040000000123401", ResourceID: "290000000001", IP: "", Activated: true}},
+	{Zone: "tk1b", MgwID: "113000000001", Sim: common.MgwSim{ICCID: "8981040000000123402", ResourceID: "290000000002", IP: "192.168.1.1", Activated: true}},
+}
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("--fix を指定してCIDRが無いとエラーになる", func(t *testing.T) {
+		_, err := validateArgs(Options{AccessToken: "Token", AccessTokenSecret: "Secret", Output: "table", Fix: true})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("--fix 無しでモバイルゲートウェイを指定するとエラーになる", func(t *testing.T) {
+		_, err := validateArgs(Options{AccessToken: "Token", AccessTokenSecret: "Secret", Output: "table", Zone: "is1b", MgwResourceID: "113000000000"})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("--fix 無しで修正対象のICCIDを指定するとエラーになる", func(t *testing.T) {
+		_, err := validateArgs(Options{AccessToken: "Token", AccessTokenSecret: "Secret", Output: "table", ICCID: []string{"8981040000000123400"}})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("CIDRの指定は省略できる", func(t *testing.T) {
+		ipNets, err := validateArgs(Options{AccessToken: "Token", AccessTokenSecret: "Secret", Output: "table"})
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if len(ipNets) != 0 {
+			t.Fatalf("unexpected ipNets...%v", ipNets)
+		}
+		t.Log("OK")
+	})
+}
+
+func TestAuditSims(t *testing.T) {
+	t.Run("不整合を種類ごとに検出する", func(t *testing.T) {
+		_, ipNet, _ := net.ParseCIDR("10.0.0.0/24")
+		findings := auditSims(testAccountSims, testAttachedSims, []*net.IPNet{ipNet})
+
+		kinds := make([]string, 0, len(findings))
+		for _, finding := range findings {
+			kinds = append(kinds, finding.Kind+":"+finding.ICCID)
+		}
+		expected := []string{
+			"unattached:8981040000000123403",
+			"unattached:8981040000000123404",
+			"no_ip:8981040000000123401",
+			"outside_cidr:8981040000000123400",
+			"outside_cidr:8981040000000123402",
+			"inactive:8981040000000123404",
+		}
+		if !reflect.DeepEqual(expected, kinds) {
+			t.Fatalf("findings expected...%v, got ...%v\n", expected, kinds)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("同じモバイルゲートウェイ内で重複するIPアドレスを検出する", func(t *testing.T) {
+		attached := append(slices.Clone(testAttachedSims),
+			AttachedSim{Zone: "is1b", MgwID: "113000000000", Sim: common.MgwSim{ICCID: "8981040000000123403", ResourceID: "290000000003", IP: "192.168.1.1", Activated: true}})
+
+		kinds := make([]string, 0)
+		for _, finding := range auditSims(testAccountSims, attached, nil) {
+			if finding.Kind == KindDuplicateIP {
+				kinds = append(kinds, finding.Kind+":"+finding.ICCID)
+			}
+		}
+		// 別のモバイルゲートウェイの 8981040000000123402 は重複として扱わない
+		expected := []string{
+			"duplicate_ip:8981040000000123400",
+			"duplicate_ip:8981040000000123403",
+		}
+		if !reflect.DeepEqual(expected, kinds) {
+			t.Fatalf("findings expected...%v, got ...%v\n", expected, kinds)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("CIDRを指定しなければ範囲外の検出を行わない", func(t *testing.T) {
+		for _, finding := range auditSims(testAccountSims, testAttachedSims, nil) {
+			if finding.Kind == KindOutsideCIDR {
+				t.Fatalf("unexpected finding...%v", finding)
+			}
+		}
+		t.Log("OK")
+	})
+}
+
+func TestPlanFixes(t *testing.T) {
+	_, ipNet, _ := net.ParseCIDR("192.168.1.0/29")
+	findings := auditSims(testAccountSims, testAttachedSims, nil)
+
+	t.Run("未登録のSIMを追加し、IPアドレスの無いSIMに未使用のIPアドレスを割り当てる", func(t *testing.T) {
+		entries, err := planFixes(findings, testAttachedSims, "is1b", "113000000000", []*net.IPNet{ipNet}, nil)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := []FixEntry{
+			{ICCID: "8981040000000123403", ResourceID: "290000000003", Zone: "is1b", MgwID: "113000000000", Attach: true, IP: "192.168.1.2"},
+			{ICCID: "8981040000000123404", ResourceID: "290000000004", Zone: "is1b", MgwID: "113000000000", Attach: true, IP: "192.168.1.3"},
+			{ICCID: "8981040000000123401", ResourceID: "290000000001", Zone: "is1b", MgwID: "113000000000", IP: "192.168.1.4"},
+		}
+		if !reflect.DeepEqual(expected, entries) {
+			t.Fatalf("entries expected...%v, got ...%v\n", expected, entries)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("追加先が無い場合は未登録のSIMを修正せず、IPアドレスの無いSIMだけを修正する", func(t *testing.T) {
+		entries, err := planFixes(findings, testAttachedSims, "", "", []*net.IPNet{ipNet}, nil)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := []FixEntry{
+			{ICCID: "8981040000000123401", ResourceID: "290000000001", Zone: "is1b", MgwID: "113000000000", IP: "192.168.1.2"},
+		}
+		if !reflect.DeepEqual(expected, entries) {
+			t.Fatalf("entries expected...%v, got ...%v\n", expected, entries)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("モバイルゲートウェイのインタフェースのネットワーク内のIPアドレスは割り当てない", func(t *testing.T) {
+		_, interfaceNet, _ := net.ParseCIDR("192.168.1.2/31")
+		interfaceNets := map[string][]*net.IPNet{"is1b/113000000000": {interfaceNet}}
+		entries, err := planFixes(findings, testAttachedSims, "is1b", "113000000000", []*net.IPNet{ipNet}, interfaceNets)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		ips := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			ips = append(ips, entry.IP)
+		}
+		expected := []string{"192.168.1.4", "192.168.1.5", "192.168.1.6"}
+		if !reflect.DeepEqual(expected, ips) {
+			t.Fatalf("ip addresses expected...%v, got ...%v\n", expected, ips)
+		}
+		t.Log("OK")
+	})
+}
+
+func TestTargetMgws(t *testing.T) {
+	findings := auditSims(testAccountSims, testAttachedSims, nil)
+
+	t.Run("追加先とIPアドレスの無いSIMのモバイルゲートウェイを重複なく返す", func(t *testing.T) {
+		expected := []TargetMgw{{Zone: "is1b", MgwID: "113000000000"}}
+		targets := targetMgws(findings, "is1b", "113000000000")
+		if !reflect.DeepEqual(expected, targets) {
+			t.Fatalf("targets expected...%v, got ...%v\n", expected, targets)
+		}
+		t.Log("OK")
+	})
+}
+
+func TestFilterFindings(t *testing.T) {
+	findings := auditSims(testAccountSims, testAttachedSims, nil)
+
+	t.Run("指定したICCIDの不整合だけを返す", func(t *testing.T) {
+		filtered := filterFindings(findings, []string{"8981040000000123401"})
+		if len(filtered) != 1 || filtered[0].Kind != KindNoIP {
+			t.Fatalf("unexpected findings...%v", filtered)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("ICCIDを指定しなければすべて返す", func(t *testing.T) {
+		if !reflect.DeepEqual(findings, filterFindings(findings, nil)) {
+			t.Fatalf("all findings are expected")
+		}
+		t.Log("OK")
+	})
+}
+
+func TestWriteFixPlan(t *testing.T) {
+	t.Run("SIMごとに修正内容を表示する", func(t *testing.T) {
+		entries := []FixEntry{
+			{ICCID: "8981040000000123403", ResourceID: "290000000003", Zone: "is1b", MgwID: "113000000000", Attach: true, IP: "192.168.1.2"},
+			{ICCID: "8981040000000123401", ResourceID: "290000000001", Zone: "is1b", MgwID: "113000000000", IP: "192.168.1.3"},
+		}
+		var buf bytes.Buffer
+		writeFixPlan(&buf, entries)
+		expected := "以下のSIM 2 枚を修正します\n" +
+			"  ICCID: 8981040000000123403, リソースID: 290000000003: モバイルゲートウェイ(113000000000)に追加, IPアドレスを設定(192.168.1.2)\n" +
+			"  ICCID: 8981040000000123401, リソースID: 290000000001: IPアドレスを設定(192.168.1.3)\n"
+		if buf.String() != expected {
+			t.Fatalf("output expected...%s, got ...%s\n", expected, buf.String())
+		}
+		t.Log("OK")
+	})
+}
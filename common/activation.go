@@ -0,0 +1,37 @@
+package common
+
+import (
+	"fmt"
+)
+
+// SetSimActivationFromList
+// リスト内の SIM を有効化(activate が true の場合)または無効化する
+// 既に指定された状態の SIM はスキップする
+func SetSimActivationFromList(accessToken string, accessTokenSecret string, sims []MgwSim, activate bool) error {
+	label := "SIM無効化"
+	if activate {
+		label = "SIM有効化"
+	}
+
+	for _, sim := range sims {
+		fmt.Printf("%s(ICCID: %s)", label, sim.ICCID)
+		if sim.Activated == activate {
+			// 既に指定された状態なのでスキップ
+			fmt.Printf("[SKIP]\n")
+			continue
+		}
+
+		var err error
+		if activate {
+			err = ActivateSim(accessToken, accessTokenSecret, sim.ResourceID)
+		} else {
+			err = DeactivateSim(accessToken, accessTokenSecret, sim.ResourceID)
+		}
+		if err != nil {
+			fmt.Printf("[FAILED]\n")
+			return err
+		}
+		fmt.Printf("[OK]\n")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// さくらのクラウド API のベース URL
+// ゾーンを埋め込んで利用する
+const apiBaseURLFormat = "https://secure.sakura.ad.jp/cloud/zone/%s/api/cloud/1.1"
+
+// SIM(commonserviceitem) はグローバルリソースなので、ゾーンは固定
+const commonServiceItemZone = "is1a"
+
+// 一覧取得 API で1回に取得する件数
+const apiPageSize = 100
+
+// ゾーンとパスから API の URL を組み立てる
+func apiURL(zone string, path string) string {
+	return fmt.Sprintf(apiBaseURLFormat, zone) + path
+}
+
+// API リクエストを送信し、レスポンスのステータスコードとボディを返す
+// body が nil でない場合は JSON にエンコードして送信する
+func requestAPI(accessToken string, accessTokenSecret string, method string, url string, body any) (int, []byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		bytesBody, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, fmt.Errorf("リクエストの組み立てに失敗しました...%s", err.Error())
+		}
+		reqBody = bytes.NewBuffer(bytesBody)
+	}
+
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return 0, nil, fmt.Errorf("HTTPクライアントの初期化に失敗しました...%s", err.Error())
+	}
+	// BASIC認証
+	req.Header = createHeadersWithBasicAuth(accessToken, accessTokenSecret)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("HTTPクライアントの実行に失敗しました...%s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("レスポンスの読み込みに失敗しました...%s", err.Error())
+	}
+
+	return resp.StatusCode, respBody, nil
+}
+
+// 正常でないレスポンスをエラーに変換する
+// action にはエラーメッセージに表示する処理名を指定する
+func apiError(statusCode int, respBody []byte, action string) error {
+	// 認証情報の間違い
+	if statusCode == http.StatusUnauthorized {
+		return fmt.Errorf("アクセストークン、アクセストークンシークレットを確認してください。%sに失敗しました", action)
+	}
+
+	// 認証エラーじゃない場合
+	var apiFatalRes SimApiIsFatalResponse
+	err := json.Unmarshal(respBody, &apiFatalRes)
+	if err != nil || apiFatalRes.ErrorMsg == "" {
+		return fmt.Errorf("%sに失敗しました...HTTPステータスコード: %d", action, statusCode)
+	}
+	// レスポンスに含まれているエラーメッセージを返す
+	return fmt.Errorf("%s: (%s)%s", apiFatalRes.Serial, apiFatalRes.Status, apiFatalRes.ErrorMsg)
+}
+
+// 結果が is_ok で返る API を呼び出し、失敗していればエラーを返す
+// action にはエラーメッセージに表示する処理名を指定する
+func requestIsOkAPI(accessToken string, accessTokenSecret string, method string, url string, body any, action string) error {
+	statusCode, respBody, err := requestAPI(accessToken, accessTokenSecret, method, url, body)
+	if err != nil {
+		return err
+	}
+
+	if statusCode < 200 || statusCode >= 300 {
+		return apiError(statusCode, respBody, action)
+	}
+
+	// 設定の成否を返す
+	var apiOkRes SimApiIsOkResponse
+	err = json.Unmarshal(respBody, &apiOkRes)
+	if err != nil {
+		return fmt.Errorf("%sのレスポンスのパースに失敗しました...%s", action, err.Error())
+	}
+
+	if !apiOkRes.IsOK {
+		return fmt.Errorf("%sが失敗しました", action)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"fmt"
+)
+
+// SIM の登録可能数の上限
+// 0 の場合は上限を確認しない
+type CapacityLimits struct {
+	// モバイルゲートウェイに登録できる SIM の上限
+	MaxMgwSims int
+	// アカウントに登録できる SIM の上限
+	MaxAccountSims int
+}
+
+// 登録前後の SIM の数
+type CapacityUsage struct {
+	// モバイルゲートウェイに登録されている SIM の数
+	MgwSims int
+	// アカウントに登録されている SIM の数
+	AccountSims int
+	// 新たに登録する SIM の数(アカウントに登録済みの SIM はスキップされるので含まない)
+	NewSims int
+}
+
+// GetCapacityUsage
+// 登録する SIM の ICCID と、アカウント・モバイルゲートウェイの SIM から登録前後の SIM の数を求める
+func GetCapacityUsage(iccids []string, accountSims []AccountSim, mgwSims []MgwSim) CapacityUsage {
+	registered := make(map[string]struct{}, len(accountSims))
+	for _, sim := range accountSims {
+		registered[sim.Status.ICCID] = struct{}{}
+	}
+
+	newSims := make(map[string]struct{})
+	for _, iccid := range iccids {
+		if _, exists := registered[iccid]; !exists {
+			newSims[iccid] = struct{}{}
+		}
+	}
+	return CapacityUsage{MgwSims: len(mgwSims), AccountSims: len(accountSims), NewSims: len(newSims)}
+}
+
+// CheckCapacity
+// 登録後の SIM の数が上限を超えないかチェックし、超える場合はその内容を返す
+func CheckCapacity(usage CapacityUsage, limits CapacityLimits) []string {
+	violations := make([]string, 0)
+	if limits.MaxMgwSims > 0 && usage.MgwSims+usage.NewSims > limits.MaxMgwSims {
+		violations = append(violations, fmt.Sprintf("モバイルゲートウェイのSIMが上限を超えます(登録済み %d 枚 + 新規 %d 枚 > 上限 %d 枚)",
+			usage.MgwSims, usage.NewSims, limits.MaxMgwSims))
+	}
+	if limits.MaxAccountSims > 0 && usage.AccountSims+usage.NewSims > limits.MaxAccountSims {
+		violations = append(violations, fmt.Sprintf("アカウントのSIMが上限を超えます(登録済み %d 枚 + 新規 %d 枚 > 上限 %d 枚)",
+			usage.AccountSims, usage.NewSims, limits.MaxAccountSims))
+	}
+	return violations
+}
@@ -0,0 +1,133 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// コマンドで指定する通信キャリアの名前と、API で使われる名前の対応
+type carrierName struct {
+	Code string
+	Name string
+}
+
+// 並び順は出力する際の順番になる
+var carrierNames = []carrierName{
+	{Code: "docomo", Name: "NTT DOCOMO"},
+	{Code: "kddi", Name: "KDDI"},
+	{Code: "softbank", Name: "SoftBank"},
+}
+
+// 通信キャリアの設定
+type NetworkOperatorConfig struct {
+	Allow bool   `json:"Allow"`
+	Name  string `json:"Name"`
+}
+
+// 通信キャリアの設定 API のリクエスト、レスポンス
+type NetworkOperatorConfigs struct {
+	NetworkOperatorConfigs []NetworkOperatorConfig `json:"NetworkOperatorConfigs"`
+}
+
+// ParseCarriers
+// 空白区切りの通信キャリアの指定をパースする
+// 同じ通信キャリアを複数回指定した場合は1つにまとめる
+func ParseCarriers(value string) ([]string, error) {
+	return ValidateCarriers(strings.Fields(value))
+}
+
+// ValidateCarriers
+// 通信キャリアの指定をチェックし、docomo, kddi, softbank の順に並べて返す
+func ValidateCarriers(carriers []string) ([]string, error) {
+	if len(carriers) == 0 {
+		return nil, fmt.Errorf("通信キャリアを1つ以上指定してください")
+	}
+
+	specified := make(map[string]struct{})
+	for _, carrier := range carriers {
+		code := strings.ToLower(carrier)
+		if !slices.ContainsFunc(carrierNames, func(c carrierName) bool { return c.Code == code }) {
+			return nil, fmt.Errorf("不正な通信キャリアです(%s)。docomo, kddi, softbank から指定してください", carrier)
+		}
+		specified[code] = struct{}{}
+	}
+
+	codes := make([]string, 0, len(specified))
+	for _, c := range carrierNames {
+		if _, exists := specified[c.Code]; exists {
+			codes = append(codes, c.Code)
+		}
+	}
+	return codes, nil
+}
+
+// GetSimCarriers
+// SIM で利用が許可されている通信キャリアを取得する
+func GetSimCarriers(accessToken string, accessTokenSecret string, simID string) ([]string, error) {
+	statusCode, body, err := requestAPI(accessToken, accessTokenSecret, "GET", simURL(simID, "/sim/network_operator_config"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, apiError(statusCode, body, "SIMの通信キャリアの取得")
+	}
+
+	var apiResponse NetworkOperatorConfigs
+	err = json.Unmarshal(body, &apiResponse)
+	if err != nil {
+		return nil, fmt.Errorf("SIMの通信キャリアのレスポンスのパースに失敗しました...%s", err.Error())
+	}
+
+	codes := make([]string, 0, len(carrierNames))
+	for _, c := range carrierNames {
+		if slices.Contains(apiResponse.NetworkOperatorConfigs, NetworkOperatorConfig{Allow: true, Name: c.Name}) {
+			codes = append(codes, c.Code)
+		}
+	}
+	return codes, nil
+}
+
+// SetSimCarriers
+// SIM で利用を許可する通信キャリアを設定する
+// 指定されていない通信キャリアは利用不可になる
+func SetSimCarriers(accessToken string, accessTokenSecret string, simID string, carriers []string) error {
+	configs := NetworkOperatorConfigs{NetworkOperatorConfigs: make([]NetworkOperatorConfig, 0, len(carrierNames))}
+	for _, c := range carrierNames {
+		configs.NetworkOperatorConfigs = append(configs.NetworkOperatorConfigs, NetworkOperatorConfig{
+			Allow: slices.Contains(carriers, c.Code),
+			Name:  c.Name,
+		})
+	}
+	return requestIsOkAPI(accessToken, accessTokenSecret, "PUT", simURL(simID, "/sim/network_operator_config"), configs, "SIMの通信キャリアの設定")
+}
+
+// SetSimCarriersFromList
+// リスト内の SIM で利用を許可する通信キャリアを設定する
+// 既に同じ設定の SIM はスキップする
+func SetSimCarriersFromList(accessToken string, accessTokenSecret string, sims []MgwSim, carriers []string) error {
+	for _, sim := range sims {
+		fmt.Printf("通信キャリア設定(ICCID: %s, %s)", sim.ICCID, strings.Join(carriers, " "))
+		current, err := GetSimCarriers(accessToken, accessTokenSecret, sim.ResourceID)
+		if err != nil {
+			fmt.Printf("[FAILED]\n")
+			return err
+		}
+		if slices.Equal(current, carriers) {
+			// 既に同じ設定なのでスキップ
+			fmt.Printf("[SKIP]\n")
+			continue
+		}
+
+		err = SetSimCarriers(accessToken, accessTokenSecret, sim.ResourceID, carriers)
+		if err != nil {
+			fmt.Printf("[FAILED]\n")
+			return err
+		}
+		fmt.Printf("[OK]\n")
+	}
+	return nil
+}
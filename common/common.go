@@ -9,18 +9,29 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // セキュアモバイルコネクト SIM 詳細 API レスポンス
 type SimAPIResponse struct {
-	Sim []struct {
-		ICCID string `json:"iccid"`
-		IP    string `json:"ip"`
-	} `json:"sim"`
-	IsOK  bool `json:"is_ok"`
-	Total int  `json:"Total"`
-	From  int  `json:"From"`
-	Count int  `json:"Count"`
+	Sim   []MgwSim `json:"sim"`
+	IsOK  bool     `json:"is_ok"`
+	Total int      `json:"Total"`
+	From  int      `json:"From"`
+	Count int      `json:"Count"`
+}
+
+// モバイルゲートウェイ配下の SIM
+type MgwSim struct {
+	ICCID         string `json:"iccid"`
+	IP            string `json:"ip"`
+	ResourceID    string `json:"resource_id"`
+	Activated     bool   `json:"activated"`
+	IMEILock      bool   `json:"imei_lock"`
+	SessionStatus string `json:"session_status"`
+	ConnectedIMEI string `json:"connected_imei"`
 }
 
 // BASIC認証のAuthorizationヘッダが設定されたhttp.Header{}インスタンスを作成
@@ -34,65 +45,131 @@ func createHeadersWithBasicAuth(user string, password string) http.Header {
 	return headers
 }
 
-// GetUsedIPAddressesInMGW
-// MGW 内での利用されている IP アドレス一覧を取得する
-// 利用されている IPアドレス一覧を取得
-func GetUsedIPAddressesInMGW(accessToken string, accessTokenSecret string, zone string, mgwID string) (map[string]struct{}, error) {
-	// BASIC認証
-	headers := createHeadersWithBasicAuth(accessToken, accessTokenSecret)
-
-	// モバイルゲートウェイ配下のSIMを全件取得するための URL の組み立て
-	baseURL := fmt.Sprintf("https://secure.sakura.ad.jp/cloud/zone/%s/api/cloud/1.1/appliance/%s/mobilegateway/sims", zone, mgwID)
-	queryParams := url.Values{}
-	queryParams.Set("From", "0")
-	fullURL := baseURL + "?" + queryParams.Encode()
-
-	req, err := http.NewRequest("GET", fullURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("HTTPクライアントの初期化に失敗しました...%s", err.Error())
-	}
-	req.Header = headers
+// GetSimsInMGW
+// MGW 配下の SIM 一覧を取得する
+// SIM の数が多い場合は、ページングして全件取得する
+func GetSimsInMGW(accessToken string, accessTokenSecret string, zone string, mgwID string) ([]MgwSim, error) {
+	sims := make([]MgwSim, 0)
+	for {
+		// モバイルゲートウェイ配下のSIMを取得するための URL の組み立て
+		queryParams := url.Values{}
+		queryParams.Set("From", strconv.Itoa(len(sims)))
+		queryParams.Set("Count", strconv.Itoa(apiPageSize))
+		fullURL := apiURL(zone, fmt.Sprintf("/appliance/%s/mobilegateway/sims", mgwID)) + "?" + queryParams.Encode()
+
+		statusCode, body, err := requestAPI(accessToken, accessTokenSecret, "GET", fullURL, nil)
+		if err != nil {
+			return nil, err
+		}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("HTTPクライアントの実行に失敗しました...%s", err.Error())
-	}
-	defer resp.Body.Close()
+		if statusCode != http.StatusOK {
+			// NotFound なので、モバイルゲートウェイのリソースID間違い
+			if statusCode == http.StatusNotFound {
+				return nil, fmt.Errorf("モバイルゲートウェイのリソースIDを確認してください。SIM情報の取得に失敗しました")
+			}
 
-	if resp.StatusCode != http.StatusOK {
-		// 認証情報の間違い
-		if resp.StatusCode == http.StatusUnauthorized {
-			return nil, fmt.Errorf("アクセストークン、アクセストークンシークレットを確認してください。SIM情報の取得に失敗しました")
+			return nil, apiError(statusCode, body, "SIM情報の取得")
 		}
 
-		// NotFound なので、モバイルゲートウェイのリソースID間違い
-		if resp.StatusCode == http.StatusNotFound {
-			return nil, fmt.Errorf("モバイルゲートウェイのリソースIDを確認してください。SIM情報の取得に失敗しました")
+		var apiResponse SimAPIResponse
+		err = json.Unmarshal(body, &apiResponse)
+		if err != nil {
+			return nil, fmt.Errorf("SIM情報のレスポンスのパースに失敗しました...%s", err.Error())
 		}
+		sims = append(sims, apiResponse.Sim...)
 
-		// その他のエラー
-		return nil, fmt.Errorf("原因不明のエラーが発生しました...HTTPステータスコード: %d", resp.StatusCode)
+		// 全件取得したか、これ以上取得できなければ終了
+		if len(apiResponse.Sim) == 0 || len(sims) >= apiResponse.Total {
+			break
+		}
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("SIM情報のレスポンスの読み込みに失敗しました...%s", err.Error())
-	}
-	var apiResponse SimAPIResponse
-	err = json.Unmarshal(body, &apiResponse)
+	return sims, nil
+}
+
+// GetUsedIPAddressesInMGW
+// MGW 内での利用されている IP アドレス一覧を取得する
+// 利用されている IPアドレス一覧を取得
+func GetUsedIPAddressesInMGW(accessToken string, accessTokenSecret string, zone string, mgwID string) (map[string]struct{}, error) {
+	sims, err := GetSimsInMGW(accessToken, accessTokenSecret, zone, mgwID)
 	if err != nil {
-		return nil, fmt.Errorf("SIM情報のレスポンスのパースに失敗しました...%s", err.Error())
+		return nil, err
 	}
 
+	return UsedIPAddressesOfSims(sims), nil
+}
+
+// UsedIPAddressesOfSims
+// SIM 一覧から利用されている IP アドレスの一覧を返す
+func UsedIPAddressesOfSims(sims []MgwSim) map[string]struct{} {
 	ipAddr := make(map[string]struct{})
 	blank := struct{}{}
-	for _, sim := range apiResponse.Sim {
+	for _, sim := range sims {
 		// map のキーを IPアドレスとし、キーのみ利用するので、バリューは空のstructとする
 		ipAddr[sim.IP] = blank
 	}
 
-	return ipAddr, nil
+	return ipAddr
+}
+
+// FilterSimsByCIDRs
+// 指定されたいずれかの CIDR に含まれる IP アドレスを持つ SIM のみを返す
+func FilterSimsByCIDRs(ipNets []*net.IPNet, sims []MgwSim) []MgwSim {
+	filtered := make([]MgwSim, 0, len(sims))
+	for _, sim := range sims {
+		ip := net.ParseIP(sim.IP)
+		if ip == nil {
+			continue
+		}
+		for _, ipNet := range ipNets {
+			if ipNet.Contains(ip) {
+				filtered = append(filtered, sim)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// IP アドレスとそれを利用している SIM の ICCID
+type UsedIPAddress struct {
+	IP        string `json:"ip_address"`
+	ICCID     string `json:"iccid"`
+	Duplicate bool   `json:"duplicate"`
+}
+
+// GetUsedIPAddressList
+// SIM 一覧から、IP アドレスと ICCID の組を IP アドレス順に並べて返す
+// 複数の SIM が同じ IP アドレスを持つ場合は Duplicate を true にする
+func GetUsedIPAddressList(sims []MgwSim) []UsedIPAddress {
+	// IP アドレスごとの SIM の数を数える
+	ipCount := make(map[string]int)
+	for _, sim := range sims {
+		ipCount[sim.IP]++
+	}
+
+	usedIPAddrs := make([]UsedIPAddress, 0, len(sims))
+	for _, sim := range sims {
+		// IP アドレスが未設定の SIM は除外する
+		if net.ParseIP(sim.IP) == nil {
+			continue
+		}
+		usedIPAddrs = append(usedIPAddrs, UsedIPAddress{IP: sim.IP, ICCID: sim.ICCID, Duplicate: ipCount[sim.IP] > 1})
+	}
+
+	sort.Slice(usedIPAddrs, func(i, j int) bool {
+		if c := compareIP(usedIPAddrs[i].IP, usedIPAddrs[j].IP); c != 0 {
+			return c < 0
+		}
+		return usedIPAddrs[i].ICCID < usedIPAddrs[j].ICCID
+	})
+
+	return usedIPAddrs
+}
+
+// IP アドレスを数値として比較する
+func compareIP(a string, b string) int {
+	return bytes.Compare(net.ParseIP(a).To16(), net.ParseIP(b).To16())
 }
 
 // GetAvailableIPAddresses
@@ -128,6 +205,132 @@ func GetAvailableIPAddresses(ip net.IP, ipNet *net.IPNet, usedIPAddresses map[st
 	return ipChan
 }
 
+// SIM に割り当て可能な IP アドレスの範囲(プライベートIPアドレス)
+var simAllowedNetworks = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"}
+
+// SIM に割り当て可能な IP アドレスを含む CIDR の最大のプレフィックス長
+// /31, /32 はネットワークアドレスとブロードキャストアドレスを除くと割り当て可能な IP アドレスが無い
+const simMaxPrefixLen = 30
+
+// ValidateSimCIDR
+// CIDR がセキュアモバイルコネクトの SIM に割り当て可能な範囲かチェックする
+func ValidateSimCIDR(ipNet *net.IPNet) error {
+	if ipNet.IP.To4() == nil {
+		return fmt.Errorf("IPv6のCIDR(%s)は指定できません。IPv4のCIDRを指定してください", ipNet.String())
+	}
+
+	ones, _ := ipNet.Mask.Size()
+	if ones > simMaxPrefixLen {
+		return fmt.Errorf("CIDR(%s)のプレフィックス長が長すぎます。割り当て可能なIPアドレスが無いため、/%d 以下を指定してください", ipNet.String(), simMaxPrefixLen)
+	}
+
+	for _, allowed := range simAllowedNetworks {
+		_, allowedNet, _ := net.ParseCIDR(allowed)
+		allowedOnes, _ := allowedNet.Mask.Size()
+		if allowedNet.Contains(ipNet.IP) && allowedOnes <= ones {
+			return nil
+		}
+	}
+	return fmt.Errorf("CIDR(%s)はSIMに割り当て可能な範囲外です。%s の範囲内で指定してください", ipNet.String(), strings.Join(simAllowedNetworks, ", "))
+}
+
+// 2つの CIDR の範囲が重複しているか判定する
+func cidrOverlaps(a *net.IPNet, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// CheckOverlappingCIDRs
+// 複数の CIDR の範囲が重複していないかチェックする
+func CheckOverlappingCIDRs(ipNets []*net.IPNet) error {
+	for i := 0; i < len(ipNets); i++ {
+		for j := i + 1; j < len(ipNets); j++ {
+			if cidrOverlaps(ipNets[i], ipNets[j]) {
+				return fmt.Errorf("CIDR %s と %s の範囲が重複しています", ipNets[i].String(), ipNets[j].String())
+			}
+		}
+	}
+	return nil
+}
+
+// GetInterfaceOverlapWarnings
+// CIDR とモバイルゲートウェイのインタフェースのネットワークが重複している場合に、
+// 警告メッセージの一覧を返す
+func GetInterfaceOverlapWarnings(ipNets []*net.IPNet, interfaceNets []*net.IPNet) []string {
+	warnings := make([]string, 0)
+	for _, ipNet := range ipNets {
+		for _, interfaceNet := range interfaceNets {
+			if cidrOverlaps(ipNet, interfaceNet) {
+				warnings = append(warnings, fmt.Sprintf("CIDR %s はモバイルゲートウェイのインタフェースのネットワーク %s と重複しています", ipNet.String(), interfaceNet.String()))
+			}
+		}
+	}
+	return warnings
+}
+
+// CountUsedIPAddressesInCIDR
+// 利用されている IP アドレスのうち、CIDR 内の割り当て可能なものの数を返す
+func CountUsedIPAddressesInCIDR(ipNet *net.IPNet, usedIPAddresses map[string]struct{}) int {
+	count := 0
+	for ipaddr := range usedIPAddresses {
+		ip := net.ParseIP(ipaddr)
+		if ip != nil && isAssignableIPAddress(ipNet, ip) {
+			count++
+		}
+	}
+	return count
+}
+
+// CIDR に含まれ、ネットワークアドレスとブロードキャストアドレスのいずれでもないか判定する
+func isAssignableIPAddress(ipNet *net.IPNet, ip net.IP) bool {
+	if !ipNet.Contains(ip) {
+		return false
+	}
+	ipaddr := ip.String()
+	return ipaddr != ipNet.IP.String() && ipaddr != broadcastAddress(ipNet)
+}
+
+// CIDR 内の IP アドレスの利用状況
+type IPAddressStats struct {
+	CIDR        string  `json:"cidr"`
+	Total       int     `json:"total"`
+	UsedInCIDR  int     `json:"used_in_cidr"`
+	Free        int     `json:"free"`
+	Utilization float64 `json:"utilization_percent"`
+	OutsideCIDR int     `json:"outside_cidr"`
+}
+
+// GetIPAddressStats
+// CIDR の パースした結果と利用されている IP アドレスから、
+// CIDR 内の利用状況を集計する
+func GetIPAddressStats(ipNet *net.IPNet, usedIPAddresses map[string]struct{}) IPAddressStats {
+	stats := IPAddressStats{CIDR: ipNet.String()}
+
+	// ネットワークアドレスとブロードキャストアドレスを除いた数が割り当て可能な総数
+	ones, bits := ipNet.Mask.Size()
+	if hostBits := bits - ones; hostBits >= 2 {
+		stats.Total = (1 << hostBits) - 2
+	}
+
+	for ipaddr := range usedIPAddresses {
+		ip := net.ParseIP(ipaddr)
+		if ip == nil {
+			// IP アドレスが未設定の SIM は数えない
+			continue
+		}
+		if !ipNet.Contains(ip) {
+			stats.OutsideCIDR++
+		}
+	}
+	stats.UsedInCIDR = CountUsedIPAddressesInCIDR(ipNet, usedIPAddresses)
+
+	stats.Free = stats.Total - stats.UsedInCIDR
+	if stats.Total > 0 {
+		stats.Utilization = float64(stats.UsedInCIDR) / float64(stats.Total) * 100
+	}
+
+	return stats
+}
+
 // ブロードキャストアドレスを返す
 func broadcastAddress(ipNet *net.IPNet) string {
 	// ipNet を参照渡しで渡してしまうと、元の値を書き換えてしまうので
@@ -164,6 +367,32 @@ func incrementIP(ip net.IP) {
 type SimRegisterInfo struct {
 	ICCID    string
 	PassCode string
+	// 空でなければ登録後に IMEI ロックを設定する
+	IMEI string
+	// 空でなければ登録後に利用する通信キャリアを設定する
+	Carriers []string
+	// SIM の名前。空の場合は ICCID を名前にする
+	Name        string
+	Description string
+	Tags        []string
+}
+
+// SIM作成APIのリクエスト
+type SimCreateAPIRequest struct {
+	CommonServiceItem struct {
+		Name        string   `json:"Name"`
+		Description string   `json:"Description,omitempty"`
+		Tags        []string `json:"Tags,omitempty"`
+		Status      struct {
+			ICCID string `json:"ICCID"`
+		} `json:"Status"`
+		Remark struct {
+			PassCode string `json:"PassCode"`
+		} `json:"Remark"`
+		Provider struct {
+			Class string `json:"Class"`
+		} `json:"Provider"`
+	} `json:"CommonServiceItem"`
 }
 
 // SIM作成APIのレスポンス
@@ -190,29 +419,27 @@ type SimApiIsFatalResponse struct {
 }
 
 // SIMの作成
-func createSim(accessToken string, accessTokenSecret string, simID string, simPasscode string) (string, error) {
+func createSim(accessToken string, accessTokenSecret string, sim SimRegisterInfo) (string, error) {
 	// BASIC認証
 	headers := createHeadersWithBasicAuth(accessToken, accessTokenSecret)
 
 	// SIM作成リクエストの組み立て
 	baseURL := "https://secure.sakura.ad.jp/cloud/zone/is1a/api/cloud/1.1/commonserviceitem"
-	body := fmt.Sprintf(`
-	{
-		"CommonServiceItem": {
-			"Name": "%s",
-			"Status": {
-				"ICCID": "%s"
-			},
-			"Remark": {
-				"PassCode": "%s"
-			},
-			"Provider": {
-				"Class": "sim"
-			}
-		}
-	}`, simID, simID, simPasscode)
-	bytesBody := []byte(body)
-	bufBody := bytes.NewBuffer(bytesBody)
+	var request SimCreateAPIRequest
+	request.CommonServiceItem.Name = sim.Name
+	if request.CommonServiceItem.Name == "" {
+		request.CommonServiceItem.Name = sim.ICCID
+	}
+	request.CommonServiceItem.Description = sim.Description
+	request.CommonServiceItem.Tags = sim.Tags
+	request.CommonServiceItem.Status.ICCID = sim.ICCID
+	request.CommonServiceItem.Remark.PassCode = sim.PassCode
+	request.CommonServiceItem.Provider.Class = "sim"
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("SIM作成リクエストの組み立てに失敗しました...%s", err.Error())
+	}
+	bufBody := bytes.NewBuffer(body)
 
 	// リクエスト送信
 	req, err := http.NewRequest("POST", baseURL, bufBody)
@@ -265,8 +492,9 @@ func createSim(accessToken string, accessTokenSecret string, simID string, simPa
 	return apiResponse.CommonServiceItem.ID, nil
 }
 
+// AssignIPAddressToSim
 // SIMのIPアドレス設定
-func assignIPAddressToSim(accessToken string, accessTokenSecret string, simID string, ipAddress string) error {
+func AssignIPAddressToSim(accessToken string, accessTokenSecret string, simID string, ipAddress string) error {
 	// BASIC認証
 	headers := createHeadersWithBasicAuth(accessToken, accessTokenSecret)
 
@@ -327,8 +555,9 @@ func assignIPAddressToSim(accessToken string, accessTokenSecret string, simID st
 	return nil
 }
 
+// AssignSimToMgw
 // モバイルゲートウェイにSIMを登録
-func assignSimToMgw(accessToken string, accessTokenSecret string, zone string, mgwID string, simID string) error {
+func AssignSimToMgw(accessToken string, accessTokenSecret string, zone string, mgwID string, simID string) error {
 	// BASIC認証
 	headers := createHeadersWithBasicAuth(accessToken, accessTokenSecret)
 
@@ -390,21 +619,56 @@ func assignSimToMgw(accessToken string, accessTokenSecret string, zone string, m
 	return nil
 }
 
+// SIM 登録のオプション
+type RegisterOptions struct {
+	// 登録後に SIM を有効化する
+	Activate bool
+	// SIM の作成や処理が完了するたびに、それまでに作成した SIM を渡して呼び出す
+	// 中断された場合に備えてレポートを書き込むために使う。エラーを返すと登録を中止する
+	OnProgress func(registered []RegisteredSim) error
+}
+
+// 登録処理で作成した SIM と、完了した処理の内容
+// 途中で失敗した場合は、完了した処理までの内容になる
+type RegisteredSim struct {
+	ICCID         string `json:"iccid"`
+	ResourceID    string `json:"resource_id"`
+	Zone          string `json:"zone"`
+	MgwResourceID string `json:"mgw_resource_id"`
+	IP            string `json:"ip"`
+	Activated     bool   `json:"activated"`
+}
+
 // 　リスト内のSIMを登録する
 func RegisterSimFromList(accessToken string, accessTokenSecret string, zone string, mgwID string, simList []SimRegisterInfo, ipList []string) error {
+	_, err := RegisterSimFromListWithOptions(accessToken, accessTokenSecret, zone, mgwID, simList, ipList, RegisterOptions{})
+	return err
+}
 
+// RegisterSimFromListWithOptions
+// リスト内のSIMを登録し、オプションで指定された追加の処理を行う
+// 作成した SIM の一覧を返す(登録済みでスキップした SIM は含まない)。エラーの場合も、それまでに作成した SIM を返す
+func RegisterSimFromListWithOptions(accessToken string, accessTokenSecret string, zone string, mgwID string, simList []SimRegisterInfo, ipList []string, opts RegisterOptions) ([]RegisteredSim, error) {
 	if len(simList) > len(ipList) {
-		return fmt.Errorf("登録対象のSIM %d 枚に対して割り当て可能なIPアドレスが %d 個しかありません", len(simList), len(ipList))
+		return nil, fmt.Errorf("登録対象のSIM %d 枚に対して割り当て可能なIPアドレスが %d 個しかありません", len(simList), len(ipList))
+	}
+
+	registered := make([]RegisteredSim, 0, len(simList))
+	progress := func() error {
+		if opts.OnProgress == nil {
+			return nil
+		}
+		return opts.OnProgress(registered)
 	}
 
 	ipListIndex := 0
 	for _, sim := range simList {
 		// SIMを作成
 		fmt.Printf("SIM登録(ICCID: %s)", sim.ICCID)
-		simResourceId, err := createSim(accessToken, accessTokenSecret, sim.ICCID, sim.PassCode)
+		simResourceId, err := createSim(accessToken, accessTokenSecret, sim)
 		if err != nil {
 			fmt.Printf("[FAILED]\n")
-			return err
+			return registered, err
 		}
 		if simResourceId == "" {
 			//登録済みだからスキップ
@@ -412,26 +676,86 @@ func RegisterSimFromList(accessToken string, accessTokenSecret string, zone stri
 			continue
 		}
 		fmt.Printf("[OK]")
+		registered = append(registered, RegisteredSim{ICCID: sim.ICCID, ResourceID: simResourceId})
+		result := &registered[len(registered)-1]
+		err = progress()
+		if err != nil {
+			fmt.Printf("\n")
+			return registered, err
+		}
 
 		// MGWにSIMを登録
 		fmt.Printf(", モバイルゲートウェイに追加")
-		err = assignSimToMgw(accessToken, accessTokenSecret, zone, mgwID, simResourceId)
+		err = AssignSimToMgw(accessToken, accessTokenSecret, zone, mgwID, simResourceId)
 		if err != nil {
 			fmt.Printf("[FAILED]\n")
-			return err
+			return registered, err
 		}
 		fmt.Printf("[OK]")
+		result.Zone = zone
+		result.MgwResourceID = mgwID
+		err = progress()
+		if err != nil {
+			fmt.Printf("\n")
+			return registered, err
+		}
 
 		// SIMにIPアドレスを設定
 		fmt.Printf(", IPアドレスを設定(%s)", ipList[ipListIndex])
-		err = assignIPAddressToSim(accessToken, accessTokenSecret, simResourceId, ipList[ipListIndex])
+		err = AssignIPAddressToSim(accessToken, accessTokenSecret, simResourceId, ipList[ipListIndex])
 		if err != nil {
 			fmt.Printf("[FAILED]\n")
-			return err
+			return registered, err
+		}
+		fmt.Printf("[OK]")
+		result.IP = ipList[ipListIndex]
+		err = progress()
+		if err != nil {
+			fmt.Printf("\n")
+			return registered, err
+		}
+
+		// SIMにIMEIロックを設定
+		if sim.IMEI != "" {
+			fmt.Printf(", IMEIロックを設定(%s)", sim.IMEI)
+			err = SetSimIMEILock(accessToken, accessTokenSecret, simResourceId, sim.IMEI)
+			if err != nil {
+				fmt.Printf("[FAILED]\n")
+				return registered, err
+			}
+			fmt.Printf("[OK]")
 		}
-		fmt.Printf("[OK]\n")
+
+		// SIMの通信キャリアを設定
+		if len(sim.Carriers) > 0 {
+			fmt.Printf(", 通信キャリアを設定(%s)", strings.Join(sim.Carriers, " "))
+			err = SetSimCarriers(accessToken, accessTokenSecret, simResourceId, sim.Carriers)
+			if err != nil {
+				fmt.Printf("[FAILED]\n")
+				return registered, err
+			}
+			fmt.Printf("[OK]")
+		}
+
+		// SIMを有効化
+		if opts.Activate {
+			fmt.Printf(", SIMを有効化")
+			err = ActivateSim(accessToken, accessTokenSecret, simResourceId)
+			if err != nil {
+				fmt.Printf("[FAILED]\n")
+				return registered, err
+			}
+			fmt.Printf("[OK]")
+			result.Activated = true
+			err = progress()
+			if err != nil {
+				fmt.Printf("\n")
+				return registered, err
+			}
+		}
+		fmt.Printf("\n")
 
 		ipListIndex++
 	}
-	return nil
+	return registered, nil
 }
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LoadICCIDListCsv
+// CSV ファイルの1列目を ICCID として読み込む
+// 2列目以降(パスコード等)は無視するので、register_sim の CSV ファイルをそのまま利用できる
+func LoadICCIDListCsv(csvPath string) ([]string, error) {
+	iccids := make([]string, 0, 100)
+
+	// CSVファイルを開く
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("CSVファイルのオープンに失敗しました...%s", err.Error())
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	// 列数は行ごとに異なっていても良い
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// ファイルの末尾に到達
+				break
+			}
+			return nil, fmt.Errorf("読み込みに失敗しました...%s", err.Error())
+		}
+		iccid := strings.TrimSpace(record[0])
+		if iccid == "" {
+			lineNo, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%d行目:ICCIDが空です", lineNo)
+		}
+		iccids = append(iccids, iccid)
+	}
+
+	return iccids, nil
+}
+
+// LoadICCIDList
+// 対象の ICCID の一覧を作成する
+// CSV ファイル(csvPath が空でない場合)の ICCID の後に iccids を続け、重複は除く
+func LoadICCIDList(csvPath string, iccids []string) ([]string, error) {
+	all := make([]string, 0, len(iccids))
+	if csvPath != "" {
+		csvICCIDs, err := LoadICCIDListCsv(csvPath)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, csvICCIDs...)
+	}
+	all = append(all, iccids...)
+
+	unique := make([]string, 0, len(all))
+	exists := make(map[string]struct{})
+	for _, iccid := range all {
+		if _, ok := exists[iccid]; ok {
+			continue
+		}
+		exists[iccid] = struct{}{}
+		unique = append(unique, iccid)
+	}
+	return unique, nil
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"fmt"
+)
+
+// IMEI ロックの設定内容
+type IMEILockInfo struct {
+	ICCID string
+	// 空の場合は IMEI ロックを解除する
+	IMEI string
+}
+
+// SetIMEILockFromList
+// リスト内の SIM に IMEI ロックを設定、または解除する
+// IMEI ロックが設定されていない SIM の解除はスキップする
+func SetIMEILockFromList(accessToken string, accessTokenSecret string, list []IMEILockInfo) error {
+	iccids := make([]string, 0, len(list))
+	for _, info := range list {
+		iccids = append(iccids, info.ICCID)
+	}
+	sims, err := FindSimsByICCID(accessToken, accessTokenSecret, iccids)
+	if err != nil {
+		return err
+	}
+
+	// FindSimsByICCID は ICCID の順に返すので、同じ添字で対応する
+	for i, info := range list {
+		sim := sims[i]
+		if info.IMEI == "" {
+			fmt.Printf("IMEIロック解除(ICCID: %s)", info.ICCID)
+			if !sim.IMEILock {
+				// IMEIロックが設定されていないのでスキップ
+				fmt.Printf("[SKIP]\n")
+				continue
+			}
+			err = ClearSimIMEILock(accessToken, accessTokenSecret, sim.ResourceID)
+		} else {
+			fmt.Printf("IMEIロック設定(ICCID: %s, IMEI: %s)", info.ICCID, info.IMEI)
+			err = SetSimIMEILock(accessToken, accessTokenSecret, sim.ResourceID, info.IMEI)
+		}
+		if err != nil {
+			fmt.Printf("[FAILED]\n")
+			return err
+		}
+		fmt.Printf("[OK]\n")
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// モバイルゲートウェイ(アプライアンス)詳細 API レスポンス
+type MgwAPIResponse struct {
+	Appliance Mgw  `json:"Appliance"`
+	IsOK      bool `json:"is_ok"`
+}
+
+// モバイルゲートウェイ(アプライアンス)一覧 API レスポンス
+type MgwListAPIResponse struct {
+	Appliances []Mgw `json:"Appliances"`
+	Total      int   `json:"Total"`
+	From       int   `json:"From"`
+	Count      int   `json:"Count"`
+	IsOK       bool  `json:"is_ok"`
+}
+
+// モバイルゲートウェイ
+type Mgw struct {
+	ID          string   `json:"ID"`
+	Name        string   `json:"Name"`
+	Description string   `json:"Description"`
+	Tags        []string `json:"Tags"`
+	Instance    struct {
+		Status string `json:"Status"`
+	} `json:"Instance"`
+	Settings struct {
+		MobileGateway struct {
+			Interfaces []MgwInterface `json:"Interfaces"`
+		} `json:"MobileGateway"`
+	} `json:"Settings"`
+}
+
+// モバイルゲートウェイのインタフェース設定
+type MgwInterface struct {
+	IPAddress      []string `json:"IPAddress"`
+	NetworkMaskLen int      `json:"NetworkMaskLen"`
+	Index          int      `json:"Index"`
+}
+
+// GetMgw
+// モバイルゲートウェイの詳細を取得する
+func GetMgw(accessToken string, accessTokenSecret string, zone string, mgwID string) (Mgw, error) {
+	url := apiURL(zone, fmt.Sprintf("/appliance/%s", mgwID))
+	statusCode, respBody, err := requestAPI(accessToken, accessTokenSecret, "GET", url, nil)
+	if err != nil {
+		return Mgw{}, err
+	}
+
+	if statusCode != http.StatusOK {
+		// NotFound なので、モバイルゲートウェイのリソースID間違い
+		if statusCode == http.StatusNotFound {
+			return Mgw{}, fmt.Errorf("モバイルゲートウェイのリソースIDを確認してください。モバイルゲートウェイ情報の取得に失敗しました")
+		}
+		return Mgw{}, apiError(statusCode, respBody, "モバイルゲートウェイ情報の取得")
+	}
+
+	var apiResponse MgwAPIResponse
+	err = json.Unmarshal(respBody, &apiResponse)
+	if err != nil {
+		return Mgw{}, fmt.Errorf("モバイルゲートウェイ情報のレスポンスのパースに失敗しました...%s", err.Error())
+	}
+
+	return apiResponse.Appliance, nil
+}
+
+// GetMgwInterfaceNetworks
+// モバイルゲートウェイのインタフェースに設定されているネットワークの一覧を取得する
+func GetMgwInterfaceNetworks(accessToken string, accessTokenSecret string, zone string, mgwID string) ([]*net.IPNet, error) {
+	mgw, err := GetMgw(accessToken, accessTokenSecret, zone, mgwID)
+	if err != nil {
+		return nil, err
+	}
+
+	ipNets := make([]*net.IPNet, 0)
+	for _, iface := range mgw.Settings.MobileGateway.Interfaces {
+		for _, ipaddr := range iface.IPAddress {
+			_, ipNet, err := net.ParseCIDR(fmt.Sprintf("%s/%d", ipaddr, iface.NetworkMaskLen))
+			if err != nil {
+				// 不正な設定は無視する
+				continue
+			}
+			ipNets = append(ipNets, ipNet)
+		}
+	}
+
+	return ipNets, nil
+}
+
+// GetMgwsInZone
+// ゾーン内のモバイルゲートウェイの一覧を取得する
+func GetMgwsInZone(accessToken string, accessTokenSecret string, zone string) ([]Mgw, error) {
+	mgws := make([]Mgw, 0)
+	for {
+		// 検索条件は JSON をクエリ文字列として渡す
+		query, err := json.Marshal(map[string]any{
+			"Filter": map[string]any{"Class": "mobilegateway"},
+			"From":   len(mgws),
+			"Count":  apiPageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("リクエストの組み立てに失敗しました...%s", err.Error())
+		}
+		fullURL := apiURL(zone, "/appliance") + "?" + url.QueryEscape(string(query))
+
+		statusCode, body, err := requestAPI(accessToken, accessTokenSecret, "GET", fullURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if statusCode != http.StatusOK {
+			return nil, apiError(statusCode, body, "モバイルゲートウェイ一覧の取得")
+		}
+
+		var apiResponse MgwListAPIResponse
+		err = json.Unmarshal(body, &apiResponse)
+		if err != nil {
+			return nil, fmt.Errorf("モバイルゲートウェイ一覧のレスポンスのパースに失敗しました...%s", err.Error())
+		}
+		mgws = append(mgws, apiResponse.Appliances...)
+
+		// 全件取得したか、これ以上取得できなければ終了
+		if len(apiResponse.Appliances) == 0 || len(mgws) >= apiResponse.Total {
+			break
+		}
+	}
+
+	return mgws, nil
+}
+
+// FindMgwIDByName
+// モバイルゲートウェイの一覧から名前が一致するもののリソースIDを返す
+// 一致するものがない場合と、複数ある場合はエラーを返す
+func FindMgwIDByName(mgws []Mgw, name string) (string, error) {
+	ids := make([]string, 0, 1)
+	for _, mgw := range mgws {
+		if mgw.Name == name {
+			ids = append(ids, mgw.ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return "", fmt.Errorf("名前が %s のモバイルゲートウェイが見つかりません", name)
+	}
+	if len(ids) > 1 {
+		return "", fmt.Errorf("名前が %s のモバイルゲートウェイが複数あります。リソースIDで指定してください...リソースID: %s", name, strings.Join(ids, ", "))
+	}
+	return ids[0], nil
+}
+
+// ResolveMgwID
+// モバイルゲートウェイの名前からリソースIDを取得する
+func ResolveMgwID(accessToken string, accessTokenSecret string, zone string, name string) (string, error) {
+	mgws, err := GetMgwsInZone(accessToken, accessTokenSecret, zone)
+	if err != nil {
+		return "", err
+	}
+	return FindMgwIDByName(mgws, name)
+}
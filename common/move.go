@@ -0,0 +1,175 @@
+package common
+
+import (
+	"fmt"
+	"net"
+)
+
+// SIM の移動内容
+type MoveSimEntry struct {
+	ICCID      string `json:"iccid"`
+	ResourceID string `json:"resource_id"`
+	OldIP      string `json:"old_ip"`
+	NewIP      string `json:"new_ip"`
+}
+
+// PlanMoveSim
+// 移動先のモバイルゲートウェイで SIM に割り当てる IP アドレスを決める
+// 移動前の IP アドレスが移動先で未使用(ipNets を指定した場合はその範囲内)であればそのまま使い、
+// そうでなければ ipNets 内の未使用の IP アドレスを順に割り当てる
+func PlanMoveSim(sims []MgwSim, destSims []MgwSim, ipNets []*net.IPNet) ([]MoveSimEntry, error) {
+	destUsed := UsedIPAddressesOfSims(destSims)
+
+	entries := make([]MoveSimEntry, 0, len(sims))
+	needNewIP := make([]int, 0)
+	for _, sim := range sims {
+		entry := MoveSimEntry{ICCID: sim.ICCID, ResourceID: sim.ResourceID, OldIP: sim.IP}
+
+		_, used := destUsed[sim.IP]
+		inCIDRs := len(ipNets) == 0 || len(FilterSimsByCIDRs(ipNets, []MgwSim{sim})) > 0
+		if sim.IP != "" && !used && inCIDRs {
+			// 移動前の IP アドレスをそのまま使う
+			entry.NewIP = sim.IP
+			destUsed[sim.IP] = struct{}{}
+		} else {
+			needNewIP = append(needNewIP, len(entries))
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(needNewIP) == 0 {
+		return entries, nil
+	}
+	if len(ipNets) == 0 {
+		return nil, fmt.Errorf("移動先で使用中のIPアドレスを持つSIMが %d 枚あります。割り当てるIPアドレスのCIDRを指定してください", len(needNewIP))
+	}
+
+	// 指定されたCIDRの順に埋めていく
+	availableIPAddrs := make([]string, 0, len(needNewIP))
+	for _, ipNet := range ipNets {
+		for ipaddr := range GetAvailableIPAddresses(ipNet.IP, ipNet, destUsed) {
+			availableIPAddrs = append(availableIPAddrs, ipaddr)
+		}
+	}
+	if len(needNewIP) > len(availableIPAddrs) {
+		return nil, fmt.Errorf("移動対象のSIM %d 枚に対して割り当て可能なIPアドレスが %d 個しかありません", len(needNewIP), len(availableIPAddrs))
+	}
+	for i, index := range needNewIP {
+		entries[index].NewIP = availableIPAddrs[i]
+	}
+
+	return entries, nil
+}
+
+// MoveSimFromList
+// 移動内容に従って SIM を移動元のモバイルゲートウェイから移動先のモバイルゲートウェイに移す
+// 途中で失敗した場合は、その SIM を移動元のモバイルゲートウェイと元の IP アドレスに戻す
+func MoveSimFromList(accessToken string, accessTokenSecret string, sourceZone string, sourceMgwID string, destZone string, destMgwID string, entries []MoveSimEntry, dryRun bool) error {
+	for _, entry := range entries {
+		fmt.Printf("SIM移動(ICCID: %s)", entry.ICCID)
+		if dryRun {
+			fmt.Printf("[DRY-RUN], IPアドレス(%s -> %s)\n", entry.OldIP, entry.NewIP)
+			continue
+		}
+		fmt.Printf("[OK]")
+
+		// SIMのIPアドレスを解除
+		if entry.OldIP != "" {
+			fmt.Printf(", IPアドレスを解除(%s)", entry.OldIP)
+			err := ClearSimIPAddress(accessToken, accessTokenSecret, entry.ResourceID)
+			if err != nil {
+				// まだ何も変更していないのでロールバックは不要
+				fmt.Printf("[FAILED]\n")
+				return err
+			}
+			fmt.Printf("[OK]")
+		}
+
+		// 移動元のMGWからSIMを削除
+		fmt.Printf(", 移動元のモバイルゲートウェイから削除")
+		err := DetachSimFromMgw(accessToken, accessTokenSecret, sourceZone, sourceMgwID, entry.ResourceID)
+		if err != nil {
+			fmt.Printf("[FAILED]\n")
+			return withMoveRollback(err, rollbackMoveSim(accessToken, accessTokenSecret, sourceZone, sourceMgwID, entry, moveStageIPCleared))
+		}
+		fmt.Printf("[OK]")
+
+		// 移動先のMGWにSIMを登録
+		fmt.Printf(", 移動先のモバイルゲートウェイに追加")
+		err = AssignSimToMgw(accessToken, accessTokenSecret, destZone, destMgwID, entry.ResourceID)
+		if err != nil {
+			fmt.Printf("[FAILED]\n")
+			return withMoveRollback(err, rollbackMoveSim(accessToken, accessTokenSecret, sourceZone, sourceMgwID, entry, moveStageDetached))
+		}
+		fmt.Printf("[OK]")
+
+		// SIMにIPアドレスを設定
+		fmt.Printf(", IPアドレスを設定(%s)", entry.NewIP)
+		err = AssignIPAddressToSim(accessToken, accessTokenSecret, entry.ResourceID, entry.NewIP)
+		if err != nil {
+			fmt.Printf("[FAILED]\n")
+			rollbackErr := detachFromDestForRollback(accessToken, accessTokenSecret, destZone, destMgwID, entry)
+			if rollbackErr == nil {
+				rollbackErr = rollbackMoveSim(accessToken, accessTokenSecret, sourceZone, sourceMgwID, entry, moveStageDetached)
+			}
+			return withMoveRollback(err, rollbackErr)
+		}
+		fmt.Printf("[OK]\n")
+	}
+	return nil
+}
+
+// ロールバック時に SIM がどこまで移動していたか
+const (
+	// IP アドレスを解除した(移動元のモバイルゲートウェイには登録されている)
+	moveStageIPCleared = iota
+	// 移動元のモバイルゲートウェイから削除した
+	moveStageDetached
+)
+
+// 移動のエラーにロールバックのエラーを付け加える
+func withMoveRollback(err error, rollbackErr error) error {
+	if rollbackErr != nil {
+		return fmt.Errorf("%s (ロールバックにも失敗しました...%s)", err.Error(), rollbackErr.Error())
+	}
+	return err
+}
+
+// 移動先のモバイルゲートウェイに追加済みの SIM を、ロールバックのために移動先から削除する
+func detachFromDestForRollback(accessToken string, accessTokenSecret string, destZone string, destMgwID string, entry MoveSimEntry) error {
+	fmt.Printf("ロールバック(ICCID: %s), 移動先のモバイルゲートウェイから削除", entry.ICCID)
+	// IP アドレスの設定に失敗しているので、IP アドレスは設定されていない場合がある。その場合は解除に失敗するのでエラーは無視する
+	_ = ClearSimIPAddress(accessToken, accessTokenSecret, entry.ResourceID)
+	err := DetachSimFromMgw(accessToken, accessTokenSecret, destZone, destMgwID, entry.ResourceID)
+	if err != nil {
+		fmt.Printf("[FAILED]\n")
+		return err
+	}
+	fmt.Printf("[OK]\n")
+	return nil
+}
+
+// SIM を移動元のモバイルゲートウェイと元の IP アドレスに戻す
+func rollbackMoveSim(accessToken string, accessTokenSecret string, sourceZone string, sourceMgwID string, entry MoveSimEntry, stage int) error {
+	fmt.Printf("ロールバック(ICCID: %s)", entry.ICCID)
+	if stage >= moveStageDetached {
+		fmt.Printf(", 移動元のモバイルゲートウェイに追加")
+		err := AssignSimToMgw(accessToken, accessTokenSecret, sourceZone, sourceMgwID, entry.ResourceID)
+		if err != nil {
+			fmt.Printf("[FAILED]\n")
+			return err
+		}
+		fmt.Printf("[OK]")
+	}
+	if entry.OldIP != "" {
+		fmt.Printf(", IPアドレスを設定(%s)", entry.OldIP)
+		err := AssignIPAddressToSim(accessToken, accessTokenSecret, entry.ResourceID, entry.OldIP)
+		if err != nil {
+			fmt.Printf("[FAILED]\n")
+			return err
+		}
+		fmt.Printf("[OK]")
+	}
+	fmt.Printf("\n")
+	return nil
+}
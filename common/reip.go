@@ -0,0 +1,132 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"slices"
+)
+
+// SIM の IP アドレス変更内容
+type ReIPEntry struct {
+	ICCID      string `json:"iccid"`
+	ResourceID string `json:"resource_id"`
+	OldIP      string `json:"old_ip"`
+	NewIP      string `json:"new_ip"`
+}
+
+// PlanReIP
+// SIM に CIDR 内の未使用の IP アドレスを順に割り当てる変更内容を作成する
+// 既に CIDR 内の IP アドレスを持つ SIM は変更しない
+func PlanReIP(sims []MgwSim, ipNets []*net.IPNet, usedIPAddresses map[string]struct{}) ([]ReIPEntry, error) {
+	inCIDRs := FilterSimsByCIDRs(ipNets, sims)
+	targets := make([]MgwSim, 0, len(sims))
+	for _, sim := range sims {
+		if slices.Contains(inCIDRs, sim) {
+			continue
+		}
+		targets = append(targets, sim)
+	}
+
+	// 指定されたCIDRの順に埋めていく
+	availableIPAddrs := make([]string, 0, len(targets))
+	for _, ipNet := range ipNets {
+		for ipaddr := range GetAvailableIPAddresses(ipNet.IP, ipNet, usedIPAddresses) {
+			availableIPAddrs = append(availableIPAddrs, ipaddr)
+		}
+	}
+	if len(targets) > len(availableIPAddrs) {
+		return nil, fmt.Errorf("変更対象のSIM %d 枚に対して割り当て可能なIPアドレスが %d 個しかありません", len(targets), len(availableIPAddrs))
+	}
+
+	entries := make([]ReIPEntry, 0, len(targets))
+	for i, sim := range targets {
+		entries = append(entries, ReIPEntry{ICCID: sim.ICCID, ResourceID: sim.ResourceID, OldIP: sim.IP, NewIP: availableIPAddrs[i]})
+	}
+	return entries, nil
+}
+
+// ValidateReIPEntries
+// IP アドレスの変更内容が、他の SIM の IP アドレスや他の変更内容と重複していないかチェックする
+// 1枚ずつ順に変更するので、他の変更対象の SIM が変更前に持っている IP アドレスも使用済みとみなす
+func ValidateReIPEntries(entries []ReIPEntry, sims []MgwSim) error {
+	used := make(map[string]string)
+	for _, sim := range sims {
+		if sim.IP == "" {
+			continue
+		}
+		used[sim.IP] = sim.ICCID
+	}
+
+	for _, entry := range entries {
+		if net.ParseIP(entry.NewIP).To4() == nil {
+			return fmt.Errorf("ICCID %s の変更後のIPアドレス(%s)が正しくありません", entry.ICCID, entry.NewIP)
+		}
+		if iccid, exists := used[entry.NewIP]; exists && iccid != entry.ICCID {
+			return fmt.Errorf("ICCID %s の変更後のIPアドレス(%s)は ICCID %s で使用されています", entry.ICCID, entry.NewIP, iccid)
+		}
+		used[entry.NewIP] = entry.ICCID
+	}
+	return nil
+}
+
+// SIM の IP アドレスを解除して、新しい IP アドレスを設定する
+func replaceSimIPAddress(accessToken string, accessTokenSecret string, simID string, oldIP string, newIP string) error {
+	if oldIP != "" {
+		err := ClearSimIPAddress(accessToken, accessTokenSecret, simID)
+		if err != nil {
+			return err
+		}
+	}
+	if newIP == "" {
+		return nil
+	}
+	return AssignIPAddressToSim(accessToken, accessTokenSecret, simID, newIP)
+}
+
+// ReIPSimFromList
+// 変更内容に従って SIM の IP アドレスを変更する
+// 途中で失敗した場合は、変更済みの SIM を元の IP アドレスに戻す
+func ReIPSimFromList(accessToken string, accessTokenSecret string, entries []ReIPEntry, dryRun bool) error {
+	for i, entry := range entries {
+		fmt.Printf("IPアドレス変更(ICCID: %s, %s -> %s)", entry.ICCID, entry.OldIP, entry.NewIP)
+		if dryRun {
+			fmt.Printf("[DRY-RUN]\n")
+			continue
+		}
+
+		err := replaceSimIPAddress(accessToken, accessTokenSecret, entry.ResourceID, entry.OldIP, entry.NewIP)
+		if err != nil {
+			fmt.Printf("[FAILED]\n")
+			// 失敗した SIM も IP アドレスが解除されている可能性があるので、ロールバックの対象にする
+			rollbackErr := rollbackReIP(accessToken, accessTokenSecret, entries[:i+1])
+			if rollbackErr != nil {
+				return fmt.Errorf("%s (ロールバックにも失敗しました...%s)", err.Error(), rollbackErr.Error())
+			}
+			return err
+		}
+		fmt.Printf("[OK]\n")
+	}
+	return nil
+}
+
+// 変更済みの SIM を逆順に元の IP アドレスに戻す
+func rollbackReIP(accessToken string, accessTokenSecret string, entries []ReIPEntry) error {
+	var lastErr error
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		fmt.Printf("ロールバック(ICCID: %s, %s -> %s)", entry.ICCID, entry.NewIP, entry.OldIP)
+		// 変更に失敗した SIM は IP アドレスが設定されていない場合があり、その場合は解除に失敗するのでエラーは無視する
+		_ = ClearSimIPAddress(accessToken, accessTokenSecret, entry.ResourceID)
+		if entry.OldIP != "" {
+			err := AssignIPAddressToSim(accessToken, accessTokenSecret, entry.ResourceID, entry.OldIP)
+			if err != nil {
+				// 他の SIM のロールバックは続ける
+				fmt.Printf("[FAILED]\n")
+				lastErr = err
+				continue
+			}
+		}
+		fmt.Printf("[OK]\n")
+	}
+	return lastErr
+}
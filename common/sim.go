@@ -0,0 +1,183 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// アカウント内の SIM(commonserviceitem)
+type AccountSim struct {
+	ID          string   `json:"ID"`
+	Name        string   `json:"Name"`
+	Description string   `json:"Description"`
+	Tags        []string `json:"Tags"`
+	Status      struct {
+		ICCID string `json:"ICCID"`
+		Sim   MgwSim `json:"sim"`
+	} `json:"Status"`
+}
+
+// SIM(commonserviceitem) 一覧 API レスポンス
+type AccountSimListAPIResponse struct {
+	CommonServiceItems []AccountSim `json:"CommonServiceItems"`
+	Total              int          `json:"Total"`
+	From               int          `json:"From"`
+	Count              int          `json:"Count"`
+	IsOK               bool         `json:"is_ok"`
+}
+
+// SimInfo
+// SIM の情報をモバイルゲートウェイ配下の SIM と同じ形式で返す
+func (s AccountSim) SimInfo() MgwSim {
+	sim := s.Status.Sim
+	sim.ICCID = s.Status.ICCID
+	sim.ResourceID = s.ID
+	return sim
+}
+
+// MatchSimsByICCID
+// ICCID の一覧に対応する SIM をアカウント内の SIM から探し、ICCID の順に返す
+// 見つからなかった ICCID は2つ目の戻り値で返す
+func MatchSimsByICCID(accountSims []AccountSim, iccids []string) ([]MgwSim, []string) {
+	simByICCID := make(map[string]AccountSim)
+	for _, sim := range accountSims {
+		simByICCID[sim.Status.ICCID] = sim
+	}
+
+	found := make([]MgwSim, 0, len(iccids))
+	notFound := make([]string, 0)
+	for _, iccid := range iccids {
+		sim, exists := simByICCID[iccid]
+		if !exists {
+			notFound = append(notFound, iccid)
+			continue
+		}
+		found = append(found, sim.SimInfo())
+	}
+	return found, notFound
+}
+
+// FindSimsByICCID
+// ICCID の一覧に対応する SIM をアカウント内から探す
+// 見つからない ICCID があればエラーを返す
+func FindSimsByICCID(accessToken string, accessTokenSecret string, iccids []string) ([]MgwSim, error) {
+	accountSims, err := GetSimsInAccount(accessToken, accessTokenSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	found, notFound := MatchSimsByICCID(accountSims, iccids)
+	if len(notFound) > 0 {
+		return nil, fmt.Errorf("アカウント内に登録されていないSIMがあります...ICCID: %s", strings.Join(notFound, ", "))
+	}
+	return found, nil
+}
+
+// SIM(commonserviceitem) の URL を組み立てる
+func simURL(simID string, path string) string {
+	return apiURL(commonServiceItemZone, fmt.Sprintf("/commonserviceitem/%s%s", simID, path))
+}
+
+// GetSimsInAccount
+// アカウント内の SIM 一覧を取得する
+// モバイルゲートウェイに登録されていない SIM も含む
+func GetSimsInAccount(accessToken string, accessTokenSecret string) ([]AccountSim, error) {
+	sims := make([]AccountSim, 0)
+	for {
+		// 検索条件は JSON をクエリ文字列として渡す
+		query, err := json.Marshal(map[string]any{
+			"Filter":  map[string]any{"Provider.Class": "sim"},
+			"Include": []string{"*", "Status.sim"},
+			"From":    len(sims),
+			"Count":   apiPageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("リクエストの組み立てに失敗しました...%s", err.Error())
+		}
+		fullURL := apiURL(commonServiceItemZone, "/commonserviceitem") + "?" + url.QueryEscape(string(query))
+
+		statusCode, body, err := requestAPI(accessToken, accessTokenSecret, "GET", fullURL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if statusCode != http.StatusOK {
+			return nil, apiError(statusCode, body, "SIM一覧の取得")
+		}
+
+		var apiResponse AccountSimListAPIResponse
+		err = json.Unmarshal(body, &apiResponse)
+		if err != nil {
+			return nil, fmt.Errorf("SIM一覧のレスポンスのパースに失敗しました...%s", err.Error())
+		}
+		sims = append(sims, apiResponse.CommonServiceItems...)
+
+		// 全件取得したか、これ以上取得できなければ終了
+		if len(apiResponse.CommonServiceItems) == 0 || len(sims) >= apiResponse.Total {
+			break
+		}
+	}
+
+	return sims, nil
+}
+
+// ClearSimIPAddress
+// SIM の IP アドレスの設定を解除する
+func ClearSimIPAddress(accessToken string, accessTokenSecret string, simID string) error {
+	return requestIsOkAPI(accessToken, accessTokenSecret, "DELETE", simURL(simID, "/sim/ip"), nil, "SIMのIPアドレス解除")
+}
+
+// DetachSimFromMgw
+// モバイルゲートウェイから SIM の登録を削除する
+func DetachSimFromMgw(accessToken string, accessTokenSecret string, zone string, mgwID string, simID string) error {
+	url := apiURL(zone, fmt.Sprintf("/appliance/%s/mobilegateway/sims/%s", mgwID, simID))
+	return requestIsOkAPI(accessToken, accessTokenSecret, "DELETE", url, nil, "モバイルゲートウェイからSIMの削除")
+}
+
+// ActivateSim
+// SIM を有効化する
+func ActivateSim(accessToken string, accessTokenSecret string, simID string) error {
+	return requestIsOkAPI(accessToken, accessTokenSecret, "PUT", simURL(simID, "/sim/activate"), nil, "SIMの有効化")
+}
+
+// DeactivateSim
+// SIM を無効化する
+func DeactivateSim(accessToken string, accessTokenSecret string, simID string) error {
+	return requestIsOkAPI(accessToken, accessTokenSecret, "PUT", simURL(simID, "/sim/deactivate"), nil, "SIMの無効化")
+}
+
+// DeleteSim
+// SIM(commonserviceitem) を削除する
+func DeleteSim(accessToken string, accessTokenSecret string, simID string) error {
+	return requestIsOkAPI(accessToken, accessTokenSecret, "DELETE", simURL(simID, ""), nil, "SIMの削除")
+}
+
+// SetSimIMEILock
+// SIM に IMEI ロックを設定する
+func SetSimIMEILock(accessToken string, accessTokenSecret string, simID string, imei string) error {
+	body := map[string]any{"sim": map[string]string{"imei": imei}}
+	return requestIsOkAPI(accessToken, accessTokenSecret, "PUT", simURL(simID, "/sim/imeilock"), body, "SIMのIMEIロック設定")
+}
+
+// ClearSimIMEILock
+// SIM の IMEI ロックを解除する
+func ClearSimIMEILock(accessToken string, accessTokenSecret string, simID string) error {
+	return requestIsOkAPI(accessToken, accessTokenSecret, "DELETE", simURL(simID, "/sim/imeilock"), nil, "SIMのIMEIロック解除")
+}
+
+// ValidateIMEI
+// IMEI が15桁の数字かチェックする
+func ValidateIMEI(imei string) error {
+	if len(imei) != 15 {
+		return fmt.Errorf("IMEIは15桁で指定してください...%s", imei)
+	}
+	for _, c := range imei {
+		if c < '0' || c > '9' {
+			return fmt.Errorf("IMEIは数字で指定してください...%s", imei)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// SIM のセッションログ
+type SimLog struct {
+	Date          string `json:"date"`
+	SessionStatus string `json:"session_status"`
+	ResourceID    string `json:"resource_id"`
+	IMEI          string `json:"imei"`
+	IMSI          string `json:"imsi"`
+}
+
+// SIM のセッションログ API レスポンス
+type SimLogAPIResponse struct {
+	Logs  []SimLog `json:"Logs"`
+	Count int      `json:"Count"`
+	IsOK  bool     `json:"is_ok"`
+}
+
+// GetSimLogs
+// SIM のセッションログを取得し、日時の古い順に返す
+func GetSimLogs(accessToken string, accessTokenSecret string, simID string) ([]SimLog, error) {
+	statusCode, body, err := requestAPI(accessToken, accessTokenSecret, "GET", simURL(simID, "/sim/sessionlog"), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, apiError(statusCode, body, "SIMのログの取得")
+	}
+
+	var apiResponse SimLogAPIResponse
+	err = json.Unmarshal(body, &apiResponse)
+	if err != nil {
+		return nil, fmt.Errorf("SIMのログのレスポンスのパースに失敗しました...%s", err.Error())
+	}
+
+	logs := apiResponse.Logs
+	if logs == nil {
+		logs = make([]SimLog, 0)
+	}
+	// 日時は RFC3339 形式なので文字列のまま比較できる
+	sort.SliceStable(logs, func(i, j int) bool { return logs[i].Date < logs[j].Date })
+
+	return logs, nil
+}
@@ -0,0 +1,325 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+)
+
+// あるべき状態
+type DesiredState struct {
+	// キーは SIM の mgw に指定する名前
+	Gateways map[string]DesiredGateway `json:"gateways" yaml:"gateways"`
+	Sims     []DesiredSim              `json:"sims" yaml:"sims"`
+}
+
+// あるべき状態のモバイルゲートウェイ
+type DesiredGateway struct {
+	Zone       string `json:"zone" yaml:"zone"`
+	ResourceID string `json:"resource_id" yaml:"resource_id"`
+}
+
+// あるべき状態の SIM
+// ポインタの項目は省略すると現在の状態を変更しない
+type DesiredSim struct {
+	ICCID string `json:"iccid" yaml:"iccid"`
+	// パスコードの参照先(env:環境変数名 または file:ファイルのパス)
+	// SIM の登録が必要な場合のみ参照する
+	PassCode string `json:"passcode" yaml:"passcode"`
+	Mgw      string `json:"mgw" yaml:"mgw"`
+	IP       string `json:"ip" yaml:"ip"`
+	// 空文字列の場合は IMEI ロックを解除する
+	IMEILock  *string `json:"imei_lock" yaml:"imei_lock"`
+	Activated *bool   `json:"activated" yaml:"activated"`
+}
+
+// 現在の状態
+type LiveState struct {
+	// アカウント内の SIM(キーは ICCID)
+	AccountSims map[string]MgwSim
+	// モバイルゲートウェイ配下の SIM(キーはあるべき状態のモバイルゲートウェイの名前)
+	MgwSims map[string][]MgwSim
+	// SIM が登録されているモバイルゲートウェイ(キーは ICCID、値は "ゾーン/リソースID")
+	// どのモバイルゲートウェイにも登録されていない SIM は含まない
+	AttachedMgws map[string]string
+}
+
+// あるべき状態にするための操作の種類
+const (
+	StateOpCreate     = "create"
+	StateOpAttach     = "attach"
+	StateOpClearIP    = "clear_ip"
+	StateOpAssignIP   = "assign_ip"
+	StateOpIMEILock   = "imei_lock"
+	StateOpIMEIUnlock = "imei_unlock"
+	StateOpActivate   = "activate"
+	StateOpDeactivate = "deactivate"
+)
+
+// 操作を実行する順番
+// SIM ごとの順番を保ったまま、IP アドレスの入れ替えができるように全ての解除を設定より先に行う
+var stateOpOrder = []string{
+	StateOpCreate,
+	StateOpAttach,
+	StateOpClearIP,
+	StateOpAssignIP,
+	StateOpIMEIUnlock,
+	StateOpIMEILock,
+	StateOpActivate,
+	StateOpDeactivate,
+}
+
+// あるべき状態にするための操作
+type StateOperation struct {
+	Kind  string
+	ICCID string
+	// SIM の登録前は空
+	ResourceID string
+	// 操作の対象のモバイルゲートウェイの名前
+	Mgw string
+	// 操作ごとの値(パスコードの参照先、IPアドレス、IMEI)
+	Value string
+}
+
+// Description
+// 操作の内容を表示用の文字列で返す
+func (op StateOperation) Description() string {
+	switch op.Kind {
+	case StateOpCreate:
+		return fmt.Sprintf("SIM登録(パスコード: %s)", op.Value)
+	case StateOpAttach:
+		return fmt.Sprintf("モバイルゲートウェイ(%s)に追加", op.Mgw)
+	case StateOpClearIP:
+		return fmt.Sprintf("IPアドレスを解除(%s)", op.Value)
+	case StateOpAssignIP:
+		return fmt.Sprintf("IPアドレスを設定(%s)", op.Value)
+	case StateOpIMEILock:
+		return fmt.Sprintf("IMEIロックを設定(%s)", op.Value)
+	case StateOpIMEIUnlock:
+		return "IMEIロックを解除"
+	case StateOpActivate:
+		return "SIMを有効化"
+	case StateOpDeactivate:
+		return "SIMを無効化"
+	}
+	return op.Kind
+}
+
+// ValidateDesiredState
+// あるべき状態の内容をチェックする
+func ValidateDesiredState(state DesiredState) error {
+	for name, gateway := range state.Gateways {
+		if !slices.Contains(ValidZones, gateway.Zone) {
+			return fmt.Errorf("モバイルゲートウェイ(%s)のゾーンが不正です。%s から指定してください", name, strings.Join(ValidZones, ", "))
+		}
+		if gateway.ResourceID == "" {
+			return fmt.Errorf("モバイルゲートウェイ(%s)のリソースIDを指定してください", name)
+		}
+	}
+
+	iccids := make(map[string]struct{})
+	ips := make(map[string]string)
+	for i, sim := range state.Sims {
+		if sim.ICCID == "" {
+			return fmt.Errorf("%d番目のSIMのICCIDを指定してください", i+1)
+		}
+		if _, exists := iccids[sim.ICCID]; exists {
+			return fmt.Errorf("ICCID %s が重複しています", sim.ICCID)
+		}
+		iccids[sim.ICCID] = struct{}{}
+
+		if _, exists := state.Gateways[sim.Mgw]; !exists {
+			return fmt.Errorf("ICCID %s のモバイルゲートウェイ(%s)が gateways に定義されていません", sim.ICCID, sim.Mgw)
+		}
+
+		if sim.IP != "" {
+			ip := net.ParseIP(sim.IP)
+			if ip == nil || ip.To4() == nil {
+				return fmt.Errorf("ICCID %s のIPアドレスが不正です...%s", sim.ICCID, sim.IP)
+			}
+			// 同じモバイルゲートウェイ内で IP アドレスは重複できない
+			key := sim.Mgw + "|" + sim.IP
+			if other, exists := ips[key]; exists {
+				return fmt.Errorf("ICCID %s と %s のIPアドレスが重複しています...%s", other, sim.ICCID, sim.IP)
+			}
+			ips[key] = sim.ICCID
+		}
+
+		if sim.IMEILock != nil && *sim.IMEILock != "" {
+			err := ValidateIMEI(*sim.IMEILock)
+			if err != nil {
+				return fmt.Errorf("ICCID %s: %s", sim.ICCID, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// ResolvePassCode
+// パスコードの参照先からパスコードを取得する
+func ResolvePassCode(ref string) (string, error) {
+	kind, value, found := strings.Cut(ref, ":")
+	if !found || value == "" {
+		return "", fmt.Errorf("パスコードの参照先は env:環境変数名 または file:ファイルのパス の形式で指定してください...%s", ref)
+	}
+
+	switch kind {
+	case "env":
+		passCode := os.Getenv(value)
+		if passCode == "" {
+			return "", fmt.Errorf("環境変数 %s が設定されていません", value)
+		}
+		return passCode, nil
+	case "file":
+		content, err := os.ReadFile(value)
+		if err != nil {
+			return "", fmt.Errorf("パスコードのファイルの読み込みに失敗しました...%s", err.Error())
+		}
+		passCode := strings.TrimSpace(string(content))
+		if passCode == "" {
+			return "", fmt.Errorf("パスコードのファイルが空です...%s", value)
+		}
+		return passCode, nil
+	}
+	return "", fmt.Errorf("パスコードの参照先は env:環境変数名 または file:ファイルのパス の形式で指定してください...%s", ref)
+}
+
+// PlanDesiredState
+// 現在の状態をあるべき状態にするための操作を、実行する順に返す
+func PlanDesiredState(state DesiredState, live LiveState) ([]StateOperation, error) {
+	// 他の SIM に指定されている IP アドレス(キーは "モバイルゲートウェイの名前|IPアドレス")
+	desiredIPs := make(map[string]string)
+	for _, desired := range state.Sims {
+		if desired.IP != "" {
+			desiredIPs[desired.Mgw+"|"+desired.IP] = desired.ICCID
+		}
+	}
+
+	ops := make([]StateOperation, 0)
+	for _, desired := range state.Sims {
+		current, registered := live.AccountSims[desired.ICCID]
+		if !registered {
+			if desired.PassCode == "" {
+				return nil, fmt.Errorf("ICCID %s のSIMは未登録です。登録するためにパスコードの参照先を指定してください", desired.ICCID)
+			}
+			ops = append(ops, StateOperation{Kind: StateOpCreate, ICCID: desired.ICCID, Value: desired.PassCode})
+			// 登録直後は無効でIPアドレスも無い
+			current = MgwSim{ICCID: desired.ICCID}
+		}
+		resourceID := current.ResourceID
+
+		// モバイルゲートウェイへの追加
+		mgwSims := live.MgwSims[desired.Mgw]
+		gateway := state.Gateways[desired.Mgw]
+		attachedMgw, attached := live.AttachedMgws[desired.ICCID]
+		if attached && attachedMgw != gateway.Zone+"/"+gateway.ResourceID {
+			return nil, fmt.Errorf("ICCID %s のSIMは別のモバイルゲートウェイ(%s)に登録されています。move_sim で移動してください", desired.ICCID, attachedMgw)
+		}
+		if !attached {
+			ops = append(ops, StateOperation{Kind: StateOpAttach, ICCID: desired.ICCID, ResourceID: resourceID, Mgw: desired.Mgw})
+		}
+
+		// IP アドレス
+		if desired.IP != "" && desired.IP != current.IP {
+			for _, sim := range mgwSims {
+				if sim.IP == desired.IP && sim.ICCID != desired.ICCID && !slices.ContainsFunc(state.Sims, func(s DesiredSim) bool { return s.ICCID == sim.ICCID }) {
+					return nil, fmt.Errorf("ICCID %s のIPアドレス %s は、あるべき状態に含まれないSIM(ICCID: %s)が使用中です", desired.ICCID, desired.IP, sim.ICCID)
+				}
+			}
+			if current.IP != "" {
+				ops = append(ops, StateOperation{Kind: StateOpClearIP, ICCID: desired.ICCID, ResourceID: resourceID, Mgw: desired.Mgw, Value: current.IP})
+			}
+			ops = append(ops, StateOperation{Kind: StateOpAssignIP, ICCID: desired.ICCID, ResourceID: resourceID, Mgw: desired.Mgw, Value: desired.IP})
+		}
+		if desired.IP == "" && current.IP != "" {
+			// IP アドレスを省略した場合は変更しないが、他の SIM に指定されている場合は解除して譲る
+			if other, exists := desiredIPs[desired.Mgw+"|"+current.IP]; exists && other != desired.ICCID {
+				ops = append(ops, StateOperation{Kind: StateOpClearIP, ICCID: desired.ICCID, ResourceID: resourceID, Mgw: desired.Mgw, Value: current.IP})
+			}
+		}
+
+		// IMEI ロック
+		// API からはロックしている IMEI が取得できないので、IMEI ロックされた SIM は
+		// 接続中の IMEI をロックしている IMEI とみなして比較する(未接続の場合は一致しているとみなす)
+		if desired.IMEILock != nil {
+			lockedIMEIDiffers := current.IMEILock && current.ConnectedIMEI != "" && current.ConnectedIMEI != *desired.IMEILock
+			if current.IMEILock && (*desired.IMEILock == "" || lockedIMEIDiffers) {
+				ops = append(ops, StateOperation{Kind: StateOpIMEIUnlock, ICCID: desired.ICCID, ResourceID: resourceID})
+			}
+			if *desired.IMEILock != "" && (!current.IMEILock || lockedIMEIDiffers) {
+				ops = append(ops, StateOperation{Kind: StateOpIMEILock, ICCID: desired.ICCID, ResourceID: resourceID, Value: *desired.IMEILock})
+			}
+		}
+
+		// 有効化、無効化
+		if desired.Activated != nil && *desired.Activated != current.Activated {
+			kind := StateOpDeactivate
+			if *desired.Activated {
+				kind = StateOpActivate
+			}
+			ops = append(ops, StateOperation{Kind: kind, ICCID: desired.ICCID, ResourceID: resourceID})
+		}
+	}
+
+	slices.SortStableFunc(ops, func(a, b StateOperation) int {
+		return slices.Index(stateOpOrder, a.Kind) - slices.Index(stateOpOrder, b.Kind)
+	})
+	return ops, nil
+}
+
+// ApplyStateOperations
+// 操作を順に実行する
+// 失敗した場合はそれ以降の操作を実行せずにエラーを返す
+func ApplyStateOperations(accessToken string, accessTokenSecret string, state DesiredState, ops []StateOperation) error {
+	// 登録した SIM のリソースID
+	resourceIDs := make(map[string]string)
+	for _, op := range ops {
+		fmt.Printf("%s(ICCID: %s)", op.Description(), op.ICCID)
+
+		resourceID := op.ResourceID
+		if resourceID == "" {
+			resourceID = resourceIDs[op.ICCID]
+		}
+
+		var err error
+		switch op.Kind {
+		case StateOpCreate:
+			var passCode string
+			passCode, err = ResolvePassCode(op.Value)
+			if err != nil {
+				break
+			}
+			resourceID, err = createSim(accessToken, accessTokenSecret, SimRegisterInfo{ICCID: op.ICCID, PassCode: passCode})
+			if err == nil && resourceID == "" {
+				err = errors.New("SIMは既に登録されています")
+			}
+			resourceIDs[op.ICCID] = resourceID
+		case StateOpAttach:
+			gateway := state.Gateways[op.Mgw]
+			err = AssignSimToMgw(accessToken, accessTokenSecret, gateway.Zone, gateway.ResourceID, resourceID)
+		case StateOpClearIP:
+			err = ClearSimIPAddress(accessToken, accessTokenSecret, resourceID)
+		case StateOpAssignIP:
+			err = AssignIPAddressToSim(accessToken, accessTokenSecret, resourceID, op.Value)
+		case StateOpIMEILock:
+			err = SetSimIMEILock(accessToken, accessTokenSecret, resourceID, op.Value)
+		case StateOpIMEIUnlock:
+			err = ClearSimIMEILock(accessToken, accessTokenSecret, resourceID)
+		case StateOpActivate:
+			err = ActivateSim(accessToken, accessTokenSecret, resourceID)
+		case StateOpDeactivate:
+			err = DeactivateSim(accessToken, accessTokenSecret, resourceID)
+		default:
+			err = fmt.Errorf("不明な操作です...%s", op.Kind)
+		}
+		if err != nil {
+			fmt.Printf("[FAILED]\n")
+			return err
+		}
+		fmt.Printf("[OK]\n")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// アクティビティモニタの集計間隔が分からない場合に使う間隔
+const defaultTrafficInterval = 5 * time.Minute
+
+// 日ごとに集計する際のタイムゾーン(日本時間)
+var TrafficLocation = time.FixedZone("JST", 9*60*60)
+
+// SIM のアクティビティモニタ API レスポンス
+type SimMonitorAPIResponse struct {
+	Data map[string]struct {
+		UplinkBPS   *float64 `json:"uplink_bps"`
+		DownlinkBPS *float64 `json:"downlink_bps"`
+	} `json:"Data"`
+	IsOK bool `json:"is_ok"`
+}
+
+// SIM の通信量の計測値
+type SimTrafficSample struct {
+	Time        time.Time
+	UplinkBPS   float64
+	DownlinkBPS float64
+}
+
+// 日ごとの通信量
+type DailyTraffic struct {
+	Date          string `json:"date"`
+	UplinkBytes   int64  `json:"uplink_bytes"`
+	DownlinkBytes int64  `json:"downlink_bytes"`
+}
+
+// GetSimTraffic
+// SIM のアクティビティモニタから期間内の通信量の計測値を取得し、時刻順に返す
+func GetSimTraffic(accessToken string, accessTokenSecret string, simID string, start time.Time, end time.Time) ([]SimTrafficSample, error) {
+	// 検索条件は JSON をクエリ文字列として渡す
+	query, err := json.Marshal(map[string]any{
+		"Start": start.Format(time.RFC3339),
+		"End":   end.Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("リクエストの組み立てに失敗しました...%s", err.Error())
+	}
+	fullURL := simURL(simID, "/sim/metrics/monitor") + "?" + url.QueryEscape(string(query))
+
+	statusCode, body, err := requestAPI(accessToken, accessTokenSecret, "GET", fullURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, apiError(statusCode, body, "SIMの通信量の取得")
+	}
+
+	var apiResponse SimMonitorAPIResponse
+	err = json.Unmarshal(body, &apiResponse)
+	if err != nil {
+		return nil, fmt.Errorf("SIMの通信量のレスポンスのパースに失敗しました...%s", err.Error())
+	}
+
+	samples := make([]SimTrafficSample, 0, len(apiResponse.Data))
+	for timestamp, value := range apiResponse.Data {
+		t, err := time.Parse(time.RFC3339, timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("SIMの通信量の時刻のパースに失敗しました...%s", err.Error())
+		}
+		sample := SimTrafficSample{Time: t}
+		// 計測値が無い時間帯は null になる
+		if value.UplinkBPS != nil {
+			sample.UplinkBPS = *value.UplinkBPS
+		}
+		if value.DownlinkBPS != nil {
+			sample.DownlinkBPS = *value.DownlinkBPS
+		}
+		samples = append(samples, sample)
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+
+	return samples, nil
+}
+
+// AggregateTrafficByDay
+// 時刻順の計測値を日ごとの通信量(バイト)に集計する
+// 計測値は次の計測時刻までの平均のビットレートとして扱う
+func AggregateTrafficByDay(samples []SimTrafficSample) []DailyTraffic {
+	daily := make([]DailyTraffic, 0)
+	interval := defaultTrafficInterval
+	for i, sample := range samples {
+		// 最後の計測値は直前の間隔を使う
+		if i+1 < len(samples) {
+			interval = samples[i+1].Time.Sub(sample.Time)
+		}
+		seconds := interval.Seconds()
+
+		date := sample.Time.In(TrafficLocation).Format(time.DateOnly)
+		if len(daily) == 0 || daily[len(daily)-1].Date != date {
+			daily = append(daily, DailyTraffic{Date: date})
+		}
+		daily[len(daily)-1].UplinkBytes += int64(sample.UplinkBPS * seconds / 8)
+		daily[len(daily)-1].DownlinkBytes += int64(sample.DownlinkBPS * seconds / 8)
+	}
+	return daily
+}
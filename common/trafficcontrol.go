@@ -0,0 +1,120 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// モバイルゲートウェイのトラフィックコントロール API の設定
+type TrafficMonitoringConfig struct {
+	TrafficQuotaInMB     int  `json:"traffic_quota_in_mb"`
+	BandWidthLimitInKbps int  `json:"bandwidth_limit_in_kbps"`
+	AutoTrafficShaping   bool `json:"auto_traffic_shaping"`
+	EmailConfig          struct {
+		Enabled bool `json:"enabled"`
+	} `json:"email_config"`
+	SlackConfig struct {
+		Enabled  bool   `json:"enabled"`
+		SlackURL string `json:"slack_url,omitempty"`
+	} `json:"slack_config"`
+}
+
+// トラフィックコントロール取得 API レスポンス
+type TrafficMonitoringAPIResponse struct {
+	TrafficMonitoringConfig *TrafficMonitoringConfig `json:"traffic_monitoring_config"`
+	IsOK                    bool                     `json:"is_ok"`
+}
+
+// トラフィックコントロール設定 API リクエスト
+type TrafficMonitoringAPIRequest struct {
+	TrafficMonitoring TrafficMonitoringConfig `json:"traffic_monitoring"`
+}
+
+// モバイルゲートウェイのトラフィックコントロールの設定
+// ファイルに保存してバージョン管理できるように、API の設定を平坦にしたもの
+type TrafficControl struct {
+	Enabled              bool   `json:"enabled"`
+	TrafficQuotaInMB     int    `json:"traffic_quota_in_mb"`
+	BandWidthLimitInKbps int    `json:"bandwidth_limit_in_kbps"`
+	AutoTrafficShaping   bool   `json:"auto_traffic_shaping"`
+	EmailNotification    bool   `json:"email_notification"`
+	SlackNotification    bool   `json:"slack_notification"`
+	SlackURL             string `json:"slack_url"`
+}
+
+// トラフィックコントロールの API の URL を組み立てる
+func trafficMonitoringURL(zone string, mgwID string) string {
+	return apiURL(zone, fmt.Sprintf("/appliance/%s/mobilegateway/traffic_monitoring", mgwID))
+}
+
+// ValidateTrafficControl
+// トラフィックコントロールの設定をチェックする
+func ValidateTrafficControl(tc TrafficControl) error {
+	if !tc.Enabled {
+		return nil
+	}
+	if tc.TrafficQuotaInMB <= 0 {
+		return errors.New("traffic_quota_in_mb には1以上を指定してください")
+	}
+	if tc.BandWidthLimitInKbps < 0 {
+		return errors.New("bandwidth_limit_in_kbps には0以上を指定してください")
+	}
+	if tc.SlackNotification && tc.SlackURL == "" {
+		return errors.New("slack_notification を有効にする場合は slack_url を指定してください")
+	}
+	return nil
+}
+
+// GetMgwTrafficControl
+// モバイルゲートウェイのトラフィックコントロールの設定を取得する
+func GetMgwTrafficControl(accessToken string, accessTokenSecret string, zone string, mgwID string) (TrafficControl, error) {
+	statusCode, body, err := requestAPI(accessToken, accessTokenSecret, "GET", trafficMonitoringURL(zone, mgwID), nil)
+	if err != nil {
+		return TrafficControl{}, err
+	}
+
+	if statusCode != http.StatusOK {
+		return TrafficControl{}, apiError(statusCode, body, "トラフィックコントロールの取得")
+	}
+
+	var apiResponse TrafficMonitoringAPIResponse
+	err = json.Unmarshal(body, &apiResponse)
+	if err != nil {
+		return TrafficControl{}, fmt.Errorf("トラフィックコントロールのレスポンスのパースに失敗しました...%s", err.Error())
+	}
+
+	// 設定されていない場合は無効
+	config := apiResponse.TrafficMonitoringConfig
+	if config == nil {
+		return TrafficControl{}, nil
+	}
+	return TrafficControl{
+		Enabled:              true,
+		TrafficQuotaInMB:     config.TrafficQuotaInMB,
+		BandWidthLimitInKbps: config.BandWidthLimitInKbps,
+		AutoTrafficShaping:   config.AutoTrafficShaping,
+		EmailNotification:    config.EmailConfig.Enabled,
+		SlackNotification:    config.SlackConfig.Enabled,
+		SlackURL:             config.SlackConfig.SlackURL,
+	}, nil
+}
+
+// SetMgwTrafficControl
+// モバイルゲートウェイのトラフィックコントロールを設定する
+// Enabled が false の場合はトラフィックコントロールを無効にする
+func SetMgwTrafficControl(accessToken string, accessTokenSecret string, zone string, mgwID string, tc TrafficControl) error {
+	if !tc.Enabled {
+		return requestIsOkAPI(accessToken, accessTokenSecret, "DELETE", trafficMonitoringURL(zone, mgwID), nil, "トラフィックコントロールの無効化")
+	}
+
+	var request TrafficMonitoringAPIRequest
+	request.TrafficMonitoring.TrafficQuotaInMB = tc.TrafficQuotaInMB
+	request.TrafficMonitoring.BandWidthLimitInKbps = tc.BandWidthLimitInKbps
+	request.TrafficMonitoring.AutoTrafficShaping = tc.AutoTrafficShaping
+	request.TrafficMonitoring.EmailConfig.Enabled = tc.EmailNotification
+	request.TrafficMonitoring.SlackConfig.Enabled = tc.SlackNotification
+	request.TrafficMonitoring.SlackConfig.SlackURL = tc.SlackURL
+	return requestIsOkAPI(accessToken, accessTokenSecret, "PUT", trafficMonitoringURL(zone, mgwID), request, "トラフィックコントロールの設定")
+}
@@ -0,0 +1,241 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// register_sim の実行結果のレポート
+type RegisterReport struct {
+	CreatedAt     string          `json:"created_at"`
+	Zone          string          `json:"zone"`
+	MgwResourceID string          `json:"mgw_resource_id"`
+	Sims          []RegisteredSim `json:"sims"`
+}
+
+// WriteRegisterReport
+// 実行結果のレポートを JSON ファイルに書き込む
+// 書き込み中に中断されても壊れないように、一時ファイルに書き込んでから置き換える
+func WriteRegisterReport(path string, report RegisterReport) error {
+	if report.Sims == nil {
+		report.Sims = make([]RegisteredSim, 0)
+	}
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("レポートの作成に失敗しました...%s", err.Error())
+	}
+	tmpPath := path + ".tmp"
+	err = os.WriteFile(tmpPath, append(body, '\n'), 0o644)
+	if err != nil {
+		return fmt.Errorf("レポートの書き込みに失敗しました...%s", err.Error())
+	}
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		return fmt.Errorf("レポートの書き込みに失敗しました...%s", err.Error())
+	}
+	return nil
+}
+
+// LoadRegisterReport
+// 実行結果のレポートを JSON ファイルから読み込む
+func LoadRegisterReport(path string) (RegisterReport, error) {
+	var report RegisterReport
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return report, fmt.Errorf("レポートの読み込みに失敗しました...%s", err.Error())
+	}
+	err = json.Unmarshal(body, &report)
+	if err != nil {
+		return report, fmt.Errorf("レポートのパースに失敗しました...%s", err.Error())
+	}
+	for i, sim := range report.Sims {
+		if sim.ICCID == "" || sim.ResourceID == "" {
+			return report, fmt.Errorf("レポートの%d件目のSIMにICCIDまたはリソースIDがありません", i+1)
+		}
+	}
+	return report, nil
+}
+
+// 取り消しの判断に使う SIM の現在の状態
+type UndoLiveSim struct {
+	ICCID     string
+	Activated bool
+	// 登録されているモバイルゲートウェイ("ゾーン/リソースID")。どこにも登録されていなければ空
+	AttachedMgw string
+	IP          string
+}
+
+// 取り消しの内容
+type UndoEntry struct {
+	Sim RegisteredSim
+	// 既に削除されている
+	Deleted bool
+	// 取り消さない理由(空の場合は取り消す)
+	SkipReason string
+	ClearIP    bool
+	Detach     bool
+	Deactivate bool
+}
+
+// Steps
+// 実際に行う取り消しの処理を表示用の文字列で返す
+func (e UndoEntry) Steps() []string {
+	if e.Deleted {
+		return []string{"削除済みのためスキップ"}
+	}
+	if e.SkipReason != "" {
+		return []string{fmt.Sprintf("スキップ(%s)", e.SkipReason)}
+	}
+	steps := make([]string, 0, 4)
+	if e.ClearIP {
+		steps = append(steps, fmt.Sprintf("IPアドレスを解除(%s)", e.Sim.IP))
+	}
+	if e.Detach {
+		steps = append(steps, fmt.Sprintf("モバイルゲートウェイ(%s)から削除", e.Sim.MgwResourceID))
+	}
+	if e.Deactivate {
+		steps = append(steps, "SIMを無効化")
+	}
+	return append(steps, "SIMを削除")
+}
+
+// GetUndoLiveState
+// アカウント内の SIM と、すべてのゾーンのモバイルゲートウェイに登録されている SIM から、
+// 取り消しの判断に使う現在の状態をリソースIDから引けるように取得する
+// 登録後に別のモバイルゲートウェイへ移動された SIM を検出するため、レポートに無いモバイルゲートウェイも参照する
+func GetUndoLiveState(accessToken string, accessTokenSecret string) (map[string]UndoLiveSim, error) {
+	accountSims, err := GetSimsInAccount(accessToken, accessTokenSecret)
+	if err != nil {
+		return nil, err
+	}
+	live := make(map[string]UndoLiveSim, len(accountSims))
+	for _, sim := range accountSims {
+		live[sim.ID] = UndoLiveSim{ICCID: sim.Status.ICCID, Activated: sim.Status.Sim.Activated}
+	}
+
+	for _, zone := range ValidZones {
+		mgws, err := GetMgwsInZone(accessToken, accessTokenSecret, zone)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", zone, err.Error())
+		}
+		for _, mgw := range mgws {
+			mgwSims, err := GetSimsInMGW(accessToken, accessTokenSecret, zone, mgw.ID)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", zone, err.Error())
+			}
+			for _, mgwSim := range mgwSims {
+				current, exists := live[mgwSim.ResourceID]
+				if !exists {
+					continue
+				}
+				current.AttachedMgw = zone + "/" + mgw.ID
+				current.IP = mgwSim.IP
+				live[mgwSim.ResourceID] = current
+			}
+		}
+	}
+	return live, nil
+}
+
+// PlanUndoRegister
+// レポートの SIM ごとに、現在の状態から実際に行う取り消しの処理を決める
+// レポートに記録されたモバイルゲートウェイと IP アドレスのままの SIM だけを取り消し、
+// 登録後に別のモバイルゲートウェイへ移動された SIM や IP アドレスが変更された SIM はスキップする
+// 既に取り消し済みの処理(IP アドレスの解除、モバイルゲートウェイからの削除、無効化)は行わない
+func PlanUndoRegister(sims []RegisteredSim, live map[string]UndoLiveSim) ([]UndoEntry, error) {
+	entries := make([]UndoEntry, 0, len(sims))
+	for _, sim := range sims {
+		entry := UndoEntry{Sim: sim}
+		current, exists := live[sim.ResourceID]
+		if !exists {
+			entry.Deleted = true
+			entries = append(entries, entry)
+			continue
+		}
+		if current.ICCID != sim.ICCID {
+			return nil, fmt.Errorf("リソースID %s のSIMのICCIDがレポートと一致しません(レポート: %s, 実際: %s)", sim.ResourceID, sim.ICCID, current.ICCID)
+		}
+
+		recordedMgw := ""
+		if sim.MgwResourceID != "" {
+			recordedMgw = sim.Zone + "/" + sim.MgwResourceID
+		}
+		switch {
+		case current.AttachedMgw != "" && current.AttachedMgw != recordedMgw:
+			entry.SkipReason = fmt.Sprintf("登録後に別のモバイルゲートウェイ(%s)に登録されています", current.AttachedMgw)
+		case current.IP != "" && current.IP != sim.IP:
+			entry.SkipReason = fmt.Sprintf("IPアドレスがレポートと異なります(レポート: %s, 実際: %s)", sim.IP, current.IP)
+		default:
+			// IP アドレスが無い、またはモバイルゲートウェイに登録されていない場合は取り消し済み
+			entry.ClearIP = current.IP != ""
+			entry.Detach = current.AttachedMgw != ""
+			entry.Deactivate = sim.Activated && current.Activated
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// UndoRegisterSimFromList
+// PlanUndoRegister で決めた取り消しの処理を、後に登録した SIM から順に行う
+// 既に削除された SIM と、スキップする SIM は [SKIP] と表示する
+func UndoRegisterSimFromList(accessToken string, accessTokenSecret string, entries []UndoEntry, dryRun bool) error {
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		sim := entry.Sim
+		fmt.Printf("SIM登録取り消し(ICCID: %s)", sim.ICCID)
+		if entry.Deleted {
+			// 削除済みなのでスキップ
+			fmt.Printf("[SKIP]\n")
+			continue
+		}
+		if entry.SkipReason != "" {
+			// 登録後に変更された SIM は取り消さない
+			fmt.Printf("[SKIP]\n")
+			fmt.Fprintf(os.Stderr, "警告: ICCID %s の登録を取り消しません...%s\n", sim.ICCID, entry.SkipReason)
+			continue
+		}
+		fmt.Printf("[OK]")
+
+		// SIMのIPアドレスを解除
+		if entry.ClearIP {
+			err := runUnregisterStep(fmt.Sprintf("IPアドレスを解除(%s)", sim.IP), dryRun, func() error {
+				return ClearSimIPAddress(accessToken, accessTokenSecret, sim.ResourceID)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// MGWからSIMを削除
+		if entry.Detach {
+			err := runUnregisterStep("モバイルゲートウェイから削除", dryRun, func() error {
+				return DetachSimFromMgw(accessToken, accessTokenSecret, sim.Zone, sim.MgwResourceID, sim.ResourceID)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// SIMを無効化
+		if entry.Deactivate {
+			err := runUnregisterStep("SIMを無効化", dryRun, func() error {
+				return DeactivateSim(accessToken, accessTokenSecret, sim.ResourceID)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// SIMを削除
+		err := runUnregisterStep("SIMを削除", dryRun, func() error {
+			return DeleteSim(accessToken, accessTokenSecret, sim.ResourceID)
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package common
+
+import (
+	"fmt"
+)
+
+// SIM 登録解除のオプション
+type UnregisterOptions struct {
+	// 登録解除後に SIM を無効化する
+	Deactivate bool
+	// 登録解除後に SIM(commonserviceitem) を削除する
+	Delete bool
+	// API を呼び出さずに実行内容のみ表示する
+	DryRun bool
+}
+
+// 登録解除の対象の SIM
+type unregisterTarget struct {
+	ResourceID string
+	IP         string
+	// モバイルゲートウェイに登録されているか
+	Attached bool
+}
+
+// 登録解除の処理を1つ実行し、結果を表示する
+func runUnregisterStep(label string, dryRun bool, step func() error) error {
+	fmt.Printf(", %s", label)
+	if dryRun {
+		fmt.Printf("[DRY-RUN]")
+		return nil
+	}
+	err := step()
+	if err != nil {
+		fmt.Printf("[FAILED]\n")
+		return err
+	}
+	fmt.Printf("[OK]")
+	return nil
+}
+
+// UnregisterSimFromList
+// リスト内の SIM のIPアドレスを解除し、モバイルゲートウェイから削除する
+// オプションにより、SIM の無効化、削除も行う
+func UnregisterSimFromList(accessToken string, accessTokenSecret string, zone string, mgwID string, iccids []string, opts UnregisterOptions) error {
+	// ICCID から SIM のリソースIDを引けるようにする
+	mgwSims, err := GetSimsInMGW(accessToken, accessTokenSecret, zone, mgwID)
+	if err != nil {
+		return err
+	}
+	targets := make(map[string]unregisterTarget)
+	for _, sim := range mgwSims {
+		targets[sim.ICCID] = unregisterTarget{ResourceID: sim.ResourceID, IP: sim.IP, Attached: true}
+	}
+
+	// モバイルゲートウェイに登録されていない SIM も無効化、削除できるように、アカウント内の SIM も参照する
+	if opts.Deactivate || opts.Delete {
+		accountSims, err := GetSimsInAccount(accessToken, accessTokenSecret)
+		if err != nil {
+			return err
+		}
+		for _, sim := range accountSims {
+			if _, exists := targets[sim.Status.ICCID]; !exists {
+				targets[sim.Status.ICCID] = unregisterTarget{ResourceID: sim.ID}
+			}
+		}
+	}
+
+	for _, iccid := range iccids {
+		fmt.Printf("SIM登録解除(ICCID: %s)", iccid)
+		target, exists := targets[iccid]
+		if !exists {
+			// 登録されていないのでスキップ
+			fmt.Printf("[SKIP]\n")
+			continue
+		}
+		fmt.Printf("[OK]")
+
+		if target.Attached {
+			// SIMのIPアドレスを解除
+			if target.IP != "" {
+				err = runUnregisterStep(fmt.Sprintf("IPアドレスを解除(%s)", target.IP), opts.DryRun, func() error {
+					return ClearSimIPAddress(accessToken, accessTokenSecret, target.ResourceID)
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			// MGWからSIMを削除
+			err = runUnregisterStep("モバイルゲートウェイから削除", opts.DryRun, func() error {
+				return DetachSimFromMgw(accessToken, accessTokenSecret, zone, mgwID, target.ResourceID)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// SIMを無効化
+		if opts.Deactivate {
+			err = runUnregisterStep("SIMを無効化", opts.DryRun, func() error {
+				return DeactivateSim(accessToken, accessTokenSecret, target.ResourceID)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// SIMを削除
+		if opts.Delete {
+			err = runUnregisterStep("SIMを削除", opts.DryRun, func() error {
+				return DeleteSim(accessToken, accessTokenSecret, target.ResourceID)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		fmt.Printf("\n")
+	}
+	return nil
+}
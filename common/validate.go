@@ -0,0 +1,28 @@
+package common
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// セキュアモバイルコネクトで指定可能なゾーン
+var ValidZones = []string{"tk1a", "tk1b", "is1a", "is1b"}
+
+// ValidateZone
+// 正しい Zone かチェックする
+func ValidateZone(zone string) error {
+	if !slices.Contains(ValidZones, zone) {
+		return fmt.Errorf("不正なゾーンです。%s から指定してください", strings.Join(ValidZones, ", "))
+	}
+	return nil
+}
+
+// ValidateOutput
+// コマンドが対応している出力形式かチェックする
+func ValidateOutput(output string, validOutputs []string) error {
+	if !slices.Contains(validOutputs, output) {
+		return fmt.Errorf("不正な出力形式です。%s から指定してください", strings.Join(validOutputs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package common
+
+import (
+	"fmt"
+	"net"
+)
+
+// SIM の検証で期待する状態
+type SimExpectation struct {
+	ICCID string
+	// 期待する IP アドレス。空の場合は IP アドレスが設定されていれば良い
+	IP string
+}
+
+// 検証結果
+const (
+	VerifyOK          = "ok"
+	VerifyNotAttached = "not_attached"
+	VerifyNoIP        = "no_ip"
+	VerifyIPMismatch  = "ip_mismatch"
+	VerifyOutsideCIDR = "outside_cidr"
+)
+
+// SIM の検証結果
+type SimVerifyResult struct {
+	ICCID      string `json:"iccid"`
+	Result     string `json:"result"`
+	ExpectedIP string `json:"expected_ip"`
+	ActualIP   string `json:"actual_ip"`
+}
+
+// OK
+// 期待する状態と一致していれば true を返す
+func (r SimVerifyResult) OK() bool {
+	return r.Result == VerifyOK
+}
+
+// Message
+// 検証結果を表示用の文字列にする
+func (r SimVerifyResult) Message() string {
+	switch r.Result {
+	case VerifyOK:
+		return fmt.Sprintf("IPアドレス: %s", r.ActualIP)
+	case VerifyNotAttached:
+		return "モバイルゲートウェイに登録されていません"
+	case VerifyNoIP:
+		return "IPアドレスが設定されていません"
+	case VerifyIPMismatch:
+		return fmt.Sprintf("IPアドレスが一致しません(期待: %s, 実際: %s)", r.ExpectedIP, r.ActualIP)
+	case VerifyOutsideCIDR:
+		return fmt.Sprintf("IPアドレスが指定されたCIDRの範囲外です(%s)", r.ActualIP)
+	}
+	return r.Result
+}
+
+// VerifySims
+// モバイルゲートウェイ配下の SIM が期待する状態になっているか検証し、期待する状態の順に結果を返す
+// ipNets を指定した場合は、IP アドレスがその範囲内であることも確認する
+func VerifySims(sims []MgwSim, expectations []SimExpectation, ipNets []*net.IPNet) []SimVerifyResult {
+	simByICCID := make(map[string]MgwSim, len(sims))
+	for _, sim := range sims {
+		simByICCID[sim.ICCID] = sim
+	}
+
+	results := make([]SimVerifyResult, 0, len(expectations))
+	for _, expectation := range expectations {
+		result := SimVerifyResult{ICCID: expectation.ICCID, ExpectedIP: expectation.IP}
+		sim, exists := simByICCID[expectation.ICCID]
+		switch {
+		case !exists:
+			result.Result = VerifyNotAttached
+		case sim.IP == "":
+			result.Result = VerifyNoIP
+		case expectation.IP != "" && sim.IP != expectation.IP:
+			result.Result = VerifyIPMismatch
+		case len(ipNets) > 0 && len(FilterSimsByCIDRs(ipNets, []MgwSim{sim})) == 0:
+			result.Result = VerifyOutsideCIDR
+		default:
+			result.Result = VerifyOK
+		}
+		if exists {
+			result.ActualIP = sim.IP
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// PrintVerifyResults
+// 検証結果を1枚ずつ表示し、期待する状態と一致しなかった SIM の数を返す
+func PrintVerifyResults(results []SimVerifyResult) int {
+	mismatches := 0
+	for _, result := range results {
+		if result.OK() {
+			fmt.Printf("検証(ICCID: %s)[OK] %s\n", result.ICCID, result.Message())
+			continue
+		}
+		mismatches++
+		fmt.Printf("検証(ICCID: %s)[NG] %s\n", result.ICCID, result.Message())
+	}
+	return mismatches
+}
@@ -0,0 +1,168 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 待機する SIM の状態
+const (
+	// SIM が有効化されている
+	WaitActivated = "activated"
+	// SIM がモバイルゲートウェイに登録され、IP アドレスが設定されている
+	WaitAttached = "attached"
+	// SIM のセッションが確立している
+	WaitSessionUp = "session_up"
+)
+
+// 待機できる SIM の状態
+var WaitStates = []string{WaitActivated, WaitAttached, WaitSessionUp}
+
+// SIM の状態の待機のオプション
+type WaitOptions struct {
+	// 待機する状態
+	State string
+	// モバイルゲートウェイのゾーンとリソースID
+	// 指定した場合はモバイルゲートウェイ配下の SIM の状態を参照する(attached の場合は必須)
+	Zone  string
+	MgwID string
+	// 状態を取得する間隔
+	Interval time.Duration
+	// 待機する最大の時間
+	Timeout time.Duration
+}
+
+// ValidateWaitOptions
+// 待機のオプションが正しいかチェックする
+func ValidateWaitOptions(opts WaitOptions) error {
+	if !slices.Contains(WaitStates, opts.State) {
+		return fmt.Errorf("不正な状態です。%s から指定してください", strings.Join(WaitStates, ", "))
+	}
+	if opts.State == WaitAttached && opts.MgwID == "" {
+		return errors.New("attached を待機する場合は、モバイルゲートウェイを指定してください")
+	}
+	if opts.Interval <= 0 || opts.Timeout <= 0 {
+		return errors.New("間隔とタイムアウトには1以上を指定してください")
+	}
+	return nil
+}
+
+// SimInState
+// SIM が待機する状態になっているか判定する
+// attached の場合は、モバイルゲートウェイ配下の SIM であることを呼び出し元で確認する
+func SimInState(sim MgwSim, state string) bool {
+	switch state {
+	case WaitActivated:
+		return sim.Activated
+	case WaitAttached:
+		return sim.IP != ""
+	case WaitSessionUp:
+		return strings.EqualFold(sim.SessionStatus, "UP")
+	}
+	return false
+}
+
+// 現在の SIM の状態を ICCID から引けるように取得する
+func getSimStates(accessToken string, accessTokenSecret string, opts WaitOptions) (map[string]MgwSim, error) {
+	states := make(map[string]MgwSim)
+	if opts.MgwID != "" {
+		sims, err := GetSimsInMGW(accessToken, accessTokenSecret, opts.Zone, opts.MgwID)
+		if err != nil {
+			return nil, err
+		}
+		for _, sim := range sims {
+			states[sim.ICCID] = sim
+		}
+		return states, nil
+	}
+
+	accountSims, err := GetSimsInAccount(accessToken, accessTokenSecret)
+	if err != nil {
+		return nil, err
+	}
+	for _, sim := range accountSims {
+		states[sim.Status.ICCID] = sim.SimInfo()
+	}
+	return states, nil
+}
+
+// getSimStates の結果
+type simStatesResult struct {
+	states map[string]MgwSim
+	err    error
+}
+
+// 現在の SIM の状態を取得する
+// 取得中でもタイムアウトや中断で戻れるように、別の goroutine で取得する
+func getSimStatesWithContext(ctx context.Context, accessToken string, accessTokenSecret string, opts WaitOptions) (map[string]MgwSim, error) {
+	err := ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan simStatesResult, 1)
+	go func() {
+		states, err := getSimStates(accessToken, accessTokenSecret, opts)
+		done <- simStatesResult{states: states, err: err}
+	}()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-done:
+		return result.states, result.err
+	}
+}
+
+// 待機を終了した理由のエラーを返す
+func waitError(ctx context.Context, opts WaitOptions, pending []string) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("タイムアウトしました。%s にならなかったSIMがあります...ICCID: %s", opts.State, strings.Join(pending, ", "))
+	}
+	return fmt.Errorf("待機を中断しました...ICCID: %s", strings.Join(pending, ", "))
+}
+
+// WaitForSimState
+// リスト内のすべての SIM が指定された状態になるまで、一定の間隔で状態を取得して待機する
+// タイムアウトまでに状態にならなかった SIM がある場合はエラーを返す。状態の取得中でもタイムアウトする
+// 状態の取得に失敗した場合は、一時的なエラーの可能性があるので警告を表示して待機を続ける
+func WaitForSimState(ctx context.Context, accessToken string, accessTokenSecret string, iccids []string, opts WaitOptions) error {
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	pending := slices.Clone(iccids)
+	for {
+		states, err := getSimStatesWithContext(ctx, accessToken, accessTokenSecret, opts)
+		if ctx.Err() != nil {
+			return waitError(ctx, opts, pending)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "警告: %s\n", err.Error())
+		} else {
+			remaining := make([]string, 0, len(pending))
+			for _, iccid := range pending {
+				sim, exists := states[iccid]
+				if exists && SimInState(sim, opts.State) {
+					fmt.Printf("状態確認(ICCID: %s, %s)[OK]\n", iccid, opts.State)
+					continue
+				}
+				remaining = append(remaining, iccid)
+			}
+			pending = remaining
+			if len(pending) == 0 {
+				return nil
+			}
+			fmt.Printf("待機中...残り %d 枚\n", len(pending))
+		}
+
+		select {
+		case <-ctx.Done():
+			return waitError(ctx, opts, pending)
+		case <-time.After(opts.Interval):
+		}
+	}
+}
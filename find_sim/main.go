@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	AccessToken       string   `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string   `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Zone              []string `long:"zone" description:"さくらのクラウドゾーン(複数指定可、省略時はすべてのゾーン)"`
+	IP                string   `long:"ip" description:"検索するSIMのIPアドレス"`
+	ICCID             string   `long:"iccid" description:"検索するSIMのICCID"`
+	Name              string   `long:"name" description:"検索するSIMの名前の一部"`
+	Output            string   `long:"output" default:"table" description:"出力形式(table, json, csv)"`
+}
+
+// 検索で見つかった SIM
+type FoundSim struct {
+	Zone          string   `json:"zone"`
+	MgwResourceID string   `json:"mgw_resource_id"`
+	MgwName       string   `json:"mgw_name"`
+	ICCID         string   `json:"iccid"`
+	ResourceID    string   `json:"resource_id"`
+	Name          string   `json:"name"`
+	Tags          []string `json:"tags"`
+	IP            string   `json:"ip"`
+	Activated     bool     `json:"activated"`
+	IMEILock      bool     `json:"imei_lock"`
+	SessionStatus string   `json:"session_status"`
+}
+
+// コマンドライン引数のバリデーションを行い、対象のゾーンを返す
+func validateArgs(opts Options) ([]string, error) {
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return nil, errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	conditions := 0
+	for _, condition := range []string{opts.IP, opts.ICCID, opts.Name} {
+		if condition != "" {
+			conditions++
+		}
+	}
+	if conditions != 1 {
+		return nil, errors.New("コマンドライン引数にIPアドレス、ICCID、名前のいずれか1つを指定してください")
+	}
+
+	if opts.IP != "" && net.ParseIP(opts.IP).To4() == nil {
+		return nil, fmt.Errorf("正しいフォーマットのIPアドレスを指定してください: %s", opts.IP)
+	}
+
+	err := common.ValidateOutput(opts.Output, []string{"table", "json", "csv"})
+	if err != nil {
+		return nil, err
+	}
+
+	// 省略時はすべてのゾーンを対象とする
+	if len(opts.Zone) == 0 {
+		return common.ValidZones, nil
+	}
+
+	zones := make([]string, 0, len(opts.Zone))
+	for _, zone := range opts.Zone {
+		err = common.ValidateZone(zone)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(zones, zone) {
+			zones = append(zones, zone)
+		}
+	}
+	return zones, nil
+}
+
+// matchSim
+// SIM が検索条件に一致するか判定する
+// 名前は大文字・小文字を区別せずに部分一致で比較する
+func matchSim(sim FoundSim, opts Options) bool {
+	switch {
+	case opts.IP != "":
+		return sim.IP == opts.IP
+	case opts.ICCID != "":
+		return sim.ICCID == opts.ICCID
+	case opts.Name != "":
+		return strings.Contains(strings.ToLower(sim.Name), strings.ToLower(opts.Name))
+	}
+	return false
+}
+
+// モバイルゲートウェイの SIM に、アカウント内の SIM の名前とタグを付けて返す
+func buildFoundSims(zone string, mgw common.Mgw, sims []common.MgwSim, accountSims map[string]common.AccountSim) []FoundSim {
+	found := make([]FoundSim, 0, len(sims))
+	for _, sim := range sims {
+		accountSim := accountSims[sim.ICCID]
+		tags := accountSim.Tags
+		if tags == nil {
+			tags = make([]string, 0)
+		}
+		found = append(found, FoundSim{
+			Zone:          zone,
+			MgwResourceID: mgw.ID,
+			MgwName:       mgw.Name,
+			ICCID:         sim.ICCID,
+			ResourceID:    sim.ResourceID,
+			Name:          accountSim.Name,
+			Tags:          tags,
+			IP:            sim.IP,
+			Activated:     sim.Activated,
+			IMEILock:      sim.IMEILock,
+			SessionStatus: sim.SessionStatus,
+		})
+	}
+	return found
+}
+
+// ゾーン内のすべてのモバイルゲートウェイから検索条件に一致する SIM を探す
+func findSimsInZone(opts Options, zone string, accountSims map[string]common.AccountSim) ([]FoundSim, error) {
+	mgws, err := common.GetMgwsInZone(opts.AccessToken, opts.AccessTokenSecret, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]FoundSim, 0)
+	for _, mgw := range mgws {
+		sims, err := common.GetSimsInMGW(opts.AccessToken, opts.AccessTokenSecret, zone, mgw.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, sim := range buildFoundSims(zone, mgw, sims, accountSims) {
+			if matchSim(sim, opts) {
+				matched = append(matched, sim)
+			}
+		}
+	}
+	return matched, nil
+}
+
+// 見つかった SIM を指定された形式で出力する
+func writeFoundSims(w io.Writer, output string, sims []FoundSim) error {
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(sims)
+	case "csv":
+		writer := csv.NewWriter(w)
+		err := writer.Write([]string{"zone", "mgw_resource_id", "mgw_name", "iccid", "resource_id", "name", "tags", "ip", "activated", "imei_lock", "session_status"})
+		if err != nil {
+			return err
+		}
+		for _, sim := range sims {
+			err = writer.Write([]string{
+				sim.Zone,
+				sim.MgwResourceID,
+				sim.MgwName,
+				sim.ICCID,
+				sim.ResourceID,
+				sim.Name,
+				strings.Join(sim.Tags, " "),
+				sim.IP,
+				strconv.FormatBool(sim.Activated),
+				strconv.FormatBool(sim.IMEILock),
+				sim.SessionStatus,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, err := fmt.Fprintln(writer, "ZONE\tMGW_RESOURCE_ID\tMGW_NAME\tICCID\tRESOURCE_ID\tNAME\tIP\tACTIVATED\tSESSION")
+		if err != nil {
+			return err
+		}
+		for _, sim := range sims {
+			_, err = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%t\t%s\n",
+				sim.Zone, sim.MgwResourceID, sim.MgwName, sim.ICCID, sim.ResourceID, sim.Name, sim.IP, sim.Activated, sim.SessionStatus)
+			if err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+	}
+}
+
+func main() {
+	// コマンドラインオプションのパース
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// コマンドライン引数を バリデーションする
+	zones, err := validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// 結果をパイプで渡せるように、標準エラー出力に出す
+	fmt.Fprintln(os.Stderr, "情報を取得しています...")
+
+	// SIM の名前とタグはアカウント内の SIM の一覧から取得する
+	accountSimList, err := common.GetSimsInAccount(opts.AccessToken, opts.AccessTokenSecret)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	accountSims := make(map[string]common.AccountSim, len(accountSimList))
+	for _, sim := range accountSimList {
+		accountSims[sim.Status.ICCID] = sim
+	}
+
+	found := make([]FoundSim, 0)
+	for _, zone := range zones {
+		sims, err := findSimsInZone(opts, zone, accountSims)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", zone, err.Error())
+			os.Exit(1)
+		}
+		found = append(found, sims...)
+	}
+
+	if len(found) == 0 {
+		fmt.Fprintln(os.Stderr, "条件に一致するSIMが見つかりませんでした")
+		os.Exit(1)
+	}
+
+	// 見つかった SIM を表示する
+	err = writeFoundSims(os.Stdout, opts.Output, found)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "結果の出力に失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// テストに使用するモバイルゲートウェイと SIM
+var testMgw = common.Mgw{ID: "113000000000", Name: "mgw01"}
+
+var testSims = []common.MgwSim{
+	{ICCID: "8981040000000123400", ResourceID: "290000000000", IP: "192.168.1.1", Activated: true, SessionStatus: "UP"},
+	{ICCID: "8981040000000123401", ResourceID: "290000000001", IP: "192.168.1.2", Activated: false, SessionStatus: "DOWN"},
+}
+
+var testAccountSims = map[string]common.AccountSim{
+	"8981040000000123400": {ID: "290000000000", Name: "Tokyo-Router-01", Tags: []string{"tokyo"}},
+}
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("検索条件が無いとエラーになる", func(t *testing.T) {
+		_, err := validateArgs(Options{AccessToken: "Token", AccessTokenSecret: "Secret", Output: "table"})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("検索条件を複数指定するとエラーになる", func(t *testing.T) {
+		_, err := validateArgs(Options{AccessToken: "Token", AccessTokenSecret: "Secret", IP: "192.168.1.1", ICCID: "8981040000000123400", Output: "table"})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("不正なIPアドレスを指定するとエラーになる", func(t *testing.T) {
+		_, err := validateArgs(Options{AccessToken: "Token", AccessTokenSecret: "Secret", IP: "192.168.1.256", Output: "table"})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("ゾーンを省略するとすべてのゾーンが対象になる", func(t *testing.T) {
+		zones, err := validateArgs(Options{AccessToken: "Token", AccessTokenSecret: "Secret", IP: "192.168.1.1", Output: "table"})
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if !reflect.DeepEqual(common.ValidZones, zones) {
+			t.Fatalf("zones expected...%v, got ...%v\n", common.ValidZones, zones)
+		}
+		t.Log("OK")
+	})
+}
+
+func TestMatchSim(t *testing.T) {
+	sims := buildFoundSims("is1b", testMgw, testSims, testAccountSims)
+
+	t.Run("IPアドレスが一致するSIMを見つける", func(t *testing.T) {
+		if !matchSim(sims[1], Options{IP: "192.168.1.2"}) || matchSim(sims[0], Options{IP: "192.168.1.2"}) {
+			t.Fatalf("unexpected match...%v", sims)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("ICCIDが一致するSIMを見つける", func(t *testing.T) {
+		if !matchSim(sims[0], Options{ICCID: "8981040000000123400"}) || matchSim(sims[1], Options{ICCID: "8981040000000123400"}) {
+			t.Fatalf("unexpected match...%v", sims)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("名前の一部が大文字・小文字を区別せずに一致するSIMを見つける", func(t *testing.T) {
+		if !matchSim(sims[0], Options{Name: "router"}) || matchSim(sims[1], Options{Name: "router"}) {
+			t.Fatalf("unexpected match...%v", sims)
+		}
+		t.Log("OK")
+	})
+}
+
+func TestWriteFoundSims(t *testing.T) {
+	t.Run("CSV形式で出力する", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeFoundSims(&buf, "csv", buildFoundSims("is1b", testMgw, testSims, testAccountSims))
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := "zone,mgw_resource_id,mgw_name,iccid,resource_id,name,tags,ip,activated,imei_lock,session_status\n" +
+			"is1b,113000000000,mgw01,8981040000000123400,290000000000,Tokyo-Router-01,tokyo,192.168.1.1,true,false,UP\n" +
+			"is1b,113000000000,mgw01,8981040000000123401,290000000001,,,192.168.1.2,false,false,DOWN\n"
+		if buf.String() != expected {
+			t.Fatalf("output expected...%s, got ...%s\n", expected, buf.String())
+		} else {
+			t.Log("OK")
+		}
+	})
+}
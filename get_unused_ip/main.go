@@ -1,65 +1,260 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	flags "github.com/jessevdk/go-flags"
 	"github.com/sakura-internet/mobile-connect-commands/common"
 	"golang.org/x/exp/slices"
+	"io"
 	"net"
 	"os"
-	"strings"
+	"strconv"
 )
 
 // コマンドライン引数
 type Options struct {
-	AccessToken       string `long:"token" description:"さくらのクラウドAPIアクセストークン"`
-	AccessTokenSecret string `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
-	Zone              string `long:"zone" description:"さくらのクラウドゾーン"`
-	CIDR              string `long:"cidr" description:"探索対象のCIDR"`
-	MgwResourceID     string `long:"mgw-resource-id" description:"モバイルゲートウェイのリソースID"`
+	AccessToken       string   `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string   `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Zone              string   `long:"zone" description:"さくらのクラウドゾーン"`
+	CIDR              []string `long:"cidr" description:"探索対象のCIDR(複数指定可, --used の場合は省略可)"`
+	MgwResourceID     string   `long:"mgw-resource-id" description:"モバイルゲートウェイのリソースID"`
+	MgwName           string   `long:"mgw-name" description:"モバイルゲートウェイの名前(リソースIDの代わりに指定)"`
+	Output            string   `long:"output" default:"text" description:"出力形式(text, json, csv)"`
+	Stats             bool     `long:"stats" description:"IPアドレスの一覧の代わりに、CIDRの利用状況を出力する"`
+	Used              bool     `long:"used" description:"未使用のIPアドレスの代わりに、使用済みのIPアドレスとSIMのICCIDを出力する"`
 }
 
-// validateZone
-// 正しい Zone かチェックする
-func validateZone(zone string) error {
-	validZones := []string{"tk1a", "tk1b", "is1a", "is1b"}
-	if !slices.Contains(validZones, zone) {
-		return fmt.Errorf("不正なゾーンです。%s から指定してください", strings.Join(validZones, ", "))
-	}
-	return nil
+// 探索結果
+type Result struct {
+	CIDR          string   `json:"cidr"`
+	UsedCount     int      `json:"used_count"`
+	FreeCount     int      `json:"free_count"`
+	FreeAddresses []string `json:"free_addresses"`
 }
 
 func validateCIDR(cidr string) (net.IP, *net.IPNet, error) {
-	// CIDR のパースだけ行う
+	// CIDR のパース
 	ip, ipNet, err := net.ParseCIDR(cidr)
 	if err != nil {
 		return ip, ipNet, fmt.Errorf("正しいフォーマットのCIDRを指定してください: %s", err.Error())
 	}
+
+	// SIM に割り当て可能な範囲かチェック
+	err = common.ValidateSimCIDR(ipNet)
+	if err != nil {
+		return ip, ipNet, err
+	}
 	return ip, ipNet, nil
 }
 
 // コマンドライン引数のバリデーションを行う
-func validateArgs(opts Options) (net.IP, *net.IPNet, error) {
-	if opts.MgwResourceID == "" {
-		return nil, nil, errors.New("コマンドライン引数にモバイルゲートウェイのリソースIDを指定してください")
+func validateArgs(opts Options) ([]*net.IPNet, error) {
+	if (opts.MgwResourceID == "") == (opts.MgwName == "") {
+		return nil, errors.New("コマンドライン引数にモバイルゲートウェイのリソースIDか名前のいずれかを指定してください")
 	}
 
 	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
-		return nil, nil, errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+		return nil, errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
 	}
 
-	err := validateZone(opts.Zone)
+	err := common.ValidateZone(opts.Zone)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	ip, ipNet, err := validateCIDR(opts.CIDR)
+	err = common.ValidateOutput(opts.Output, []string{"text", "json", "csv"})
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return ip, ipNet, nil
+	if opts.Stats && opts.Used {
+		return nil, errors.New("--stats と --used は同時に指定できません")
+	}
+
+	// --used の場合、CIDR を省略するとすべての使用済みIPアドレスを対象にする
+	if len(opts.CIDR) == 0 && !opts.Used {
+		return nil, errors.New("コマンドライン引数にCIDRを指定してください")
+	}
+
+	ipNets := make([]*net.IPNet, 0, len(opts.CIDR))
+	for _, cidr := range opts.CIDR {
+		_, ipNet, err := validateCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+
+	err = common.CheckOverlappingCIDRs(ipNets)
+	if err != nil {
+		return nil, err
+	}
+
+	return ipNets, nil
+}
+
+// CIDR ごとの探索結果を指定された形式で出力する
+// CIDR が1つの場合、json 形式では配列ではなくオブジェクトを出力し、text 形式では CIDR の見出しを付けない
+func writeResults(w io.Writer, output string, results []Result) error {
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if len(results) == 1 {
+			return encoder.Encode(results[0])
+		}
+		return encoder.Encode(results)
+	case "csv":
+		writer := csv.NewWriter(w)
+		err := writer.Write([]string{"cidr", "ip_address"})
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			for _, ipaddr := range result.FreeAddresses {
+				err = writer.Write([]string{result.CIDR, ipaddr})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		for i, result := range results {
+			if len(results) > 1 {
+				// CIDR ごとに空行で区切り、見出しを付ける
+				if i > 0 {
+					_, err := fmt.Fprintln(w)
+					if err != nil {
+						return err
+					}
+				}
+				_, err := fmt.Fprintf(w, "CIDR: %s\n", result.CIDR)
+				if err != nil {
+					return err
+				}
+			}
+			for _, ipaddr := range result.FreeAddresses {
+				_, err := fmt.Fprintln(w, ipaddr)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// CIDR ごとの利用状況を指定された形式で出力する
+// CIDR が1つの場合、json 形式では配列ではなくオブジェクトを出力する
+func writeStats(w io.Writer, output string, stats []common.IPAddressStats) error {
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if len(stats) == 1 {
+			return encoder.Encode(stats[0])
+		}
+		return encoder.Encode(stats)
+	case "csv":
+		writer := csv.NewWriter(w)
+		err := writer.Write([]string{"cidr", "total", "used_in_cidr", "free", "utilization_percent", "outside_cidr"})
+		if err != nil {
+			return err
+		}
+		for _, s := range stats {
+			err = writer.Write([]string{
+				s.CIDR,
+				strconv.Itoa(s.Total),
+				strconv.Itoa(s.UsedInCIDR),
+				strconv.Itoa(s.Free),
+				strconv.FormatFloat(s.Utilization, 'f', 1, 64),
+				strconv.Itoa(s.OutsideCIDR),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		for i, s := range stats {
+			// CIDR ごとに空行で区切る
+			if i > 0 {
+				_, err := fmt.Fprintln(w)
+				if err != nil {
+					return err
+				}
+			}
+			_, err := fmt.Fprintf(w, "CIDR: %s\n"+
+				"割り当て可能なIPアドレス数: %d\n"+
+				"使用済みのIPアドレス数: %d\n"+
+				"空きIPアドレス数: %d\n"+
+				"使用率: %.1f%%\n"+
+				"CIDR外のIPアドレスを持つSIMの数: %d\n",
+				s.CIDR, s.Total, s.UsedInCIDR, s.Free, s.Utilization, s.OutsideCIDR)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// 使用済みの IP アドレスと ICCID を指定された形式で出力する
+func writeUsedIPAddresses(w io.Writer, output string, usedIPAddrs []common.UsedIPAddress) error {
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(usedIPAddrs)
+	case "csv":
+		writer := csv.NewWriter(w)
+		err := writer.Write([]string{"ip_address", "iccid", "duplicate"})
+		if err != nil {
+			return err
+		}
+		for _, used := range usedIPAddrs {
+			err = writer.Write([]string{used.IP, used.ICCID, strconv.FormatBool(used.Duplicate)})
+			if err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		for _, used := range usedIPAddrs {
+			line := fmt.Sprintf("%s\t%s", used.IP, used.ICCID)
+			if used.Duplicate {
+				// 複数のSIMが同じIPアドレスを持っている
+				line += "\t[重複]"
+			}
+			_, err := fmt.Fprintln(w, line)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// 指定されたいずれの CIDR にも含まれない IP アドレスを持つ SIM の数を返す
+func countOutsideAllCIDRs(ipNets []*net.IPNet, usedIPAddresses map[string]struct{}) int {
+	count := 0
+	for ipaddr := range usedIPAddresses {
+		ip := net.ParseIP(ipaddr)
+		if ip == nil {
+			continue
+		}
+		if !slices.ContainsFunc(ipNets, func(ipNet *net.IPNet) bool { return ipNet.Contains(ip) }) {
+			count++
+		}
+	}
+	return count
 }
 
 func main() {
@@ -74,7 +269,7 @@ func main() {
 	}
 
 	// コマンドライン引数を バリデーションする
-	ip, ipNet, err := validateArgs(opts)
+	ipNets, err := validateArgs(opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
 		os.Exit(1)
@@ -82,17 +277,90 @@ func main() {
 
 	// コマンドラインオプションのチェックが終わったら、実行していることを
 	// ユーザに伝えるため情報を出す
-	fmt.Println("情報を取得しています...")
+	// 結果をパイプで渡せるように、標準エラー出力に出す
+	fmt.Fprintln(os.Stderr, "情報を取得しています...")
+
+	// 名前が指定されていればモバイルゲートウェイのリソースIDを取得する
+	if opts.MgwName != "" {
+		opts.MgwResourceID, err = common.ResolveMgwID(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	}
+
+	sims, err := common.GetSimsInMGW(opts.AccessToken, opts.AccessTokenSecret,
+		opts.Zone, opts.MgwResourceID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	mgwIPAddrs := common.UsedIPAddressesOfSims(sims)
 
-	mgwIPAddrs, err := common.GetUsedIPAddressesInMGW(opts.AccessToken, opts.AccessTokenSecret,
+	// CIDR がモバイルゲートウェイのインタフェースのネットワークと重複していたら警告する
+	interfaceNets, err := common.GetMgwInterfaceNetworks(opts.AccessToken, opts.AccessTokenSecret,
 		opts.Zone, opts.MgwResourceID)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
 		os.Exit(1)
 	}
-	for ipaddr := range common.GetAvailableIPAddresses(ip, ipNet, mgwIPAddrs) {
-		// 取得可能なIPアドレスを表示する
-		fmt.Println(ipaddr)
+	for _, warning := range common.GetInterfaceOverlapWarnings(ipNets, interfaceNets) {
+		fmt.Fprintf(os.Stderr, "警告: %s\n", warning)
+	}
+
+	if opts.Used {
+		// 使用済みのIPアドレスとICCIDを表示する
+		// CIDR が指定されていればCIDR内のものに絞り込む
+		targetSims := sims
+		if len(ipNets) > 0 {
+			targetSims = common.FilterSimsByCIDRs(ipNets, sims)
+		}
+		usedIPAddrs := common.GetUsedIPAddressList(targetSims)
+		err = writeUsedIPAddresses(os.Stdout, opts.Output, usedIPAddrs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "結果の出力に失敗しました...%s\n", err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if opts.Stats {
+		// CIDRごとの利用状況を表示する
+		// CIDR外の数は、指定されたいずれのCIDRにも含まれないものを数える
+		outsideCount := countOutsideAllCIDRs(ipNets, mgwIPAddrs)
+		stats := make([]common.IPAddressStats, 0, len(ipNets))
+		for _, ipNet := range ipNets {
+			s := common.GetIPAddressStats(ipNet, mgwIPAddrs)
+			s.OutsideCIDR = outsideCount
+			stats = append(stats, s)
+		}
+		err = writeStats(os.Stdout, opts.Output, stats)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "結果の出力に失敗しました...%s\n", err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	results := make([]Result, 0, len(ipNets))
+	for _, ipNet := range ipNets {
+		result := Result{
+			CIDR:          ipNet.String(),
+			UsedCount:     common.CountUsedIPAddressesInCIDR(ipNet, mgwIPAddrs),
+			FreeAddresses: make([]string, 0),
+		}
+		for ipaddr := range common.GetAvailableIPAddresses(ipNet.IP, ipNet, mgwIPAddrs) {
+			result.FreeAddresses = append(result.FreeAddresses, ipaddr)
+		}
+		result.FreeCount = len(result.FreeAddresses)
+		results = append(results, result)
+	}
+
+	// 取得可能なIPアドレスを表示する
+	err = writeResults(os.Stdout, opts.Output, results)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "結果の出力に失敗しました...%s\n", err.Error())
+		os.Exit(1)
 	}
 	os.Exit(0)
 }
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"github.com/sakura-internet/mobile-connect-commands/common"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -40,6 +43,301 @@ func TestAvailableIPAddress(t *testing.T) {
 	})
 }
 
+func TestCountUsedIPAddressesInCIDR(t *testing.T) {
+	t.Run("CIDRに含まれる使用済みIPアドレスのみを数える", func(t *testing.T) {
+		_, ipNet, _ := net.ParseCIDR("192.168.1.0/29")
+		blank := struct{}{}
+
+		simAssignedIPAddresses := map[string]struct{}{
+			"192.168.1.1":  blank,
+			"192.168.1.2":  blank,
+			"192.168.1.10": blank,
+			"":             blank,
+		}
+
+		actual := common.CountUsedIPAddressesInCIDR(ipNet, simAssignedIPAddresses)
+		if actual != 2 {
+			t.Fatalf("used count expected...%d, got ...%d\n", 2, actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("ネットワークアドレスとブロードキャストアドレスは数えない", func(t *testing.T) {
+		_, ipNet, _ := net.ParseCIDR("192.168.1.0/29")
+		blank := struct{}{}
+
+		simAssignedIPAddresses := map[string]struct{}{
+			"192.168.1.0": blank,
+			"192.168.1.1": blank,
+			"192.168.1.7": blank,
+		}
+
+		actual := common.CountUsedIPAddressesInCIDR(ipNet, simAssignedIPAddresses)
+		if actual != 1 {
+			t.Fatalf("used count expected...%d, got ...%d\n", 1, actual)
+		}
+		stats := common.GetIPAddressStats(ipNet, simAssignedIPAddresses)
+		if stats.UsedInCIDR != actual {
+			t.Fatalf("used count expected...%d, got ...%d\n", actual, stats.UsedInCIDR)
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestGetIPAddressStats(t *testing.T) {
+	t.Run("CIDRの利用状況を集計する", func(t *testing.T) {
+		_, ipNet, _ := net.ParseCIDR("192.168.1.0/29")
+		blank := struct{}{}
+
+		// SIM にアサイン済の IP アドレス(CIDR外と未設定を含む)
+		simAssignedIPAddresses := map[string]struct{}{
+			"192.168.1.1": blank,
+			"192.168.1.2": blank,
+			"192.168.1.3": blank,
+			"192.168.2.1": blank,
+			"10.0.0.1":    blank,
+			"":            blank,
+		}
+
+		expected := common.IPAddressStats{
+			CIDR:        "192.168.1.0/29",
+			Total:       6,
+			UsedInCIDR:  3,
+			Free:        3,
+			Utilization: 50,
+			OutsideCIDR: 2,
+		}
+
+		actual := common.GetIPAddressStats(ipNet, simAssignedIPAddresses)
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("stats expected...%v, got ...%v\n", expected, actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("割り当て可能なIPアドレスが無いCIDRでは使用率が0になる", func(t *testing.T) {
+		_, ipNet, _ := net.ParseCIDR("192.168.1.1/32")
+
+		actual := common.GetIPAddressStats(ipNet, map[string]struct{}{})
+		if actual.Total != 0 || actual.Utilization != 0 {
+			t.Fatalf("empty stats expected, got ...%v\n", actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestWriteStats(t *testing.T) {
+	t.Run("csv形式ではヘッダ付きで1行出力する", func(t *testing.T) {
+		stats := common.IPAddressStats{CIDR: "192.168.1.0/29", Total: 6, UsedInCIDR: 2, Free: 4, Utilization: 100.0 / 3, OutsideCIDR: 1}
+
+		var buf bytes.Buffer
+		err := writeStats(&buf, "csv", []common.IPAddressStats{stats})
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+
+		expected := "cidr,total,used_in_cidr,free,utilization_percent,outside_cidr\n192.168.1.0/29,6,2,4,33.3,1\n"
+		if buf.String() != expected {
+			t.Fatalf("output expected...%q, got ...%q\n", expected, buf.String())
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("json形式ではCIDRが1つならオブジェクトを出力する", func(t *testing.T) {
+		stats := common.IPAddressStats{CIDR: "192.168.1.0/29", Total: 6, UsedInCIDR: 2, Free: 4, Utilization: 100.0 / 3, OutsideCIDR: 1}
+
+		var buf bytes.Buffer
+		err := writeStats(&buf, "json", []common.IPAddressStats{stats})
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+
+		var actual common.IPAddressStats
+		err = json.Unmarshal(buf.Bytes(), &actual)
+		if err != nil {
+			t.Fatalf("json parse error...%s", err.Error())
+		}
+		if !reflect.DeepEqual(stats, actual) {
+			t.Fatalf("stats expected...%v, got ...%v\n", stats, actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestWriteResults(t *testing.T) {
+	results := []Result{
+		{
+			CIDR:          "192.168.1.0/29",
+			UsedCount:     4,
+			FreeCount:     2,
+			FreeAddresses: []string{"192.168.1.5", "192.168.1.6"},
+		},
+		{
+			CIDR:          "192.168.2.0/30",
+			UsedCount:     1,
+			FreeCount:     1,
+			FreeAddresses: []string{"192.168.2.2"},
+		},
+	}
+
+	t.Run("text形式ではCIDRが1つならIPアドレスのみを1行ずつ出力する", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeResults(&buf, "text", results[:1])
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+
+		expected := "192.168.1.5\n192.168.1.6\n"
+		if buf.String() != expected {
+			t.Fatalf("output expected...%q, got ...%q\n", expected, buf.String())
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("text形式ではCIDRが複数ならCIDRごとに見出しを付けて空行で区切る", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeResults(&buf, "text", results)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+
+		expected := "CIDR: 192.168.1.0/29\n192.168.1.5\n192.168.1.6\n\nCIDR: 192.168.2.0/30\n192.168.2.2\n"
+		if buf.String() != expected {
+			t.Fatalf("output expected...%q, got ...%q\n", expected, buf.String())
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("json形式ではCIDRが1つならオブジェクトを出力する", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeResults(&buf, "json", results[:1])
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+
+		var actual Result
+		err = json.Unmarshal(buf.Bytes(), &actual)
+		if err != nil {
+			t.Fatalf("json parse error...%s", err.Error())
+		}
+		if !reflect.DeepEqual(results[0], actual) {
+			t.Fatalf("result expected...%v, got ...%v\n", results[0], actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("json形式ではCIDRが複数ならCIDRごとに件数を含めて配列で出力する", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeResults(&buf, "json", results)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+
+		var actual []Result
+		err = json.Unmarshal(buf.Bytes(), &actual)
+		if err != nil {
+			t.Fatalf("json parse error...%s", err.Error())
+		}
+		if !reflect.DeepEqual(results, actual) {
+			t.Fatalf("result expected...%v, got ...%v\n", results, actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("csv形式ではヘッダ付きで出力する", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeResults(&buf, "csv", results)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 4 || lines[0] != "cidr,ip_address" || lines[1] != "192.168.1.0/29,192.168.1.5" || lines[3] != "192.168.2.0/30,192.168.2.2" {
+			t.Fatalf("unexpected csv output...%q\n", buf.String())
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestGetUsedIPAddressList(t *testing.T) {
+	t.Run("使用済みのIPアドレスをICCIDと共にIPアドレス順に返し、重複を検出する", func(t *testing.T) {
+		sims := []common.MgwSim{
+			{ICCID: "8981040000000123403", IP: "192.168.1.10"},
+			{ICCID: "8981040000000123401", IP: "192.168.1.2"},
+			{ICCID: "8981040000000123402", IP: "192.168.1.10"},
+			{ICCID: "8981040000000123404", IP: ""},
+		}
+
+		expected := []common.UsedIPAddress{
+			{IP: "192.168.1.2", ICCID: "8981040000000123401", Duplicate: false},
+			{IP: "192.168.1.10", ICCID: "8981040000000123402", Duplicate: true},
+			{IP: "192.168.1.10", ICCID: "8981040000000123403", Duplicate: true},
+		}
+
+		actual := common.GetUsedIPAddressList(sims)
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("used ip addresses expected...%v, got ...%v\n", expected, actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestWriteUsedIPAddresses(t *testing.T) {
+	t.Run("text形式では重複しているIPアドレスに印を付ける", func(t *testing.T) {
+		usedIPAddrs := []common.UsedIPAddress{
+			{IP: "192.168.1.2", ICCID: "8981040000000123401", Duplicate: false},
+			{IP: "192.168.1.10", ICCID: "8981040000000123402", Duplicate: true},
+		}
+
+		var buf bytes.Buffer
+		err := writeUsedIPAddresses(&buf, "text", usedIPAddrs)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+
+		expected := "192.168.1.2\t8981040000000123401\n192.168.1.10\t8981040000000123402\t[重複]\n"
+		if buf.String() != expected {
+			t.Fatalf("output expected...%q, got ...%q\n", expected, buf.String())
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestCountOutsideAllCIDRs(t *testing.T) {
+	t.Run("いずれのCIDRにも含まれないIPアドレスのみを数える", func(t *testing.T) {
+		_, ipNet1, _ := net.ParseCIDR("192.168.1.0/29")
+		_, ipNet2, _ := net.ParseCIDR("192.168.2.0/29")
+		blank := struct{}{}
+
+		simAssignedIPAddresses := map[string]struct{}{
+			"192.168.1.1": blank,
+			"192.168.2.1": blank,
+			"10.0.0.1":    blank,
+			"":            blank,
+		}
+
+		actual := countOutsideAllCIDRs([]*net.IPNet{ipNet1, ipNet2}, simAssignedIPAddresses)
+		if actual != 1 {
+			t.Fatalf("outside count expected...%d, got ...%d\n", 1, actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
 func TestValidateCIDR(t *testing.T) {
 	t.Run("不正なCIDRを入力したらエラーが返る", func(t *testing.T) {
 		cidr := "192.168.1.0.0/29"
@@ -63,11 +361,58 @@ func TestValidateCIDR(t *testing.T) {
 			t.Log("OK")
 		}
 	})
+
+	t.Run("SIMに割り当てできないCIDRはエラーが返る", func(t *testing.T) {
+		invalidCIDRs := []string{
+			// IPv6
+			"fd00::/64",
+			// グローバルIPアドレス
+			"203.0.113.0/24",
+			// プライベートIPアドレスの範囲をまたぐ
+			"172.0.0.0/8",
+			// 割り当て可能なIPアドレスが無い
+			"192.168.1.0/31",
+			"192.168.1.1/32",
+		}
+		for _, cidr := range invalidCIDRs {
+			_, _, err := validateCIDR(cidr)
+			if err == nil {
+				t.Fatalf("error is expected for %s", cidr)
+			}
+		}
+		t.Log("OK")
+	})
+}
+
+func TestGetInterfaceOverlapWarnings(t *testing.T) {
+	t.Run("インタフェースのネットワークと重複するCIDRのみ警告する", func(t *testing.T) {
+		_, ipNet1, _ := net.ParseCIDR("192.168.0.0/28")
+		_, ipNet2, _ := net.ParseCIDR("192.168.1.0/28")
+		_, interfaceNet, _ := net.ParseCIDR("192.168.0.0/24")
+
+		warnings := common.GetInterfaceOverlapWarnings([]*net.IPNet{ipNet1, ipNet2}, []*net.IPNet{interfaceNet})
+		if len(warnings) != 1 || !strings.Contains(warnings[0], "192.168.0.0/28") {
+			t.Fatalf("one warning for 192.168.0.0/28 is expected, got ...%v", warnings)
+		} else {
+			t.Log("OK")
+		}
+	})
 }
 func TestValidateZone(t *testing.T) {
 	t.Run("不正なゾーンを入力したら、エラーが返る", func(t *testing.T) {
 		zone := "tk3a"
-		err := validateZone(zone)
+		err := common.ValidateZone(zone)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+func TestValidateOutput(t *testing.T) {
+	t.Run("不正な出力形式を入力したら、エラーが返る", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", CIDR: []string{"192.168.1.0/29"}, MgwResourceID: "aaaaaaa", Output: "xml"}
+		_, err := validateArgs(options)
 		if err != nil {
 			t.Log("OK")
 		} else {
@@ -77,12 +422,87 @@ func TestValidateZone(t *testing.T) {
 }
 func TestValidateArgs(t *testing.T) {
 	t.Run("アクセストークン、アクセストークンシークレットが無いとエラーになる", func(t *testing.T) {
-		options := Options{AccessToken: "", AccessTokenSecret: "", Zone: "is1a", CIDR: "192.168.1.0/29", MgwResourceID: "aaaaaaa"}
-		_, _, err := validateArgs(options)
+		options := Options{AccessToken: "", AccessTokenSecret: "", Zone: "is1a", CIDR: []string{"192.168.1.0/29"}, MgwResourceID: "aaaaaaa"}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("複数のCIDRを指定すると順番通りに返る", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", CIDR: []string{"192.168.2.0/29", "192.168.1.0/29"}, MgwResourceID: "aaaaaaa", Output: "text"}
+		ipNets, err := validateArgs(options)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if len(ipNets) != 2 || ipNets[0].String() != "192.168.2.0/29" || ipNets[1].String() != "192.168.1.0/29" {
+			t.Fatalf("unexpected CIDRs...%v", ipNets)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("--statsと--usedを同時に指定するとエラーになる", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", CIDR: []string{"192.168.1.0/29"}, MgwResourceID: "aaaaaaa", Output: "text", Stats: true, Used: true}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("CIDRを指定しないとエラーになる", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", MgwResourceID: "aaaaaaa", Output: "text"}
+		_, err := validateArgs(options)
 		if err != nil {
 			t.Log("OK")
 		} else {
 			t.Fatalf("error is expected")
 		}
 	})
+
+	t.Run("--usedの場合はCIDRを省略できる", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", MgwResourceID: "aaaaaaa", Output: "text", Used: true}
+		ipNets, err := validateArgs(options)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if len(ipNets) != 0 {
+			t.Fatalf("empty CIDRs expected, got ...%v", ipNets)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("範囲が重複するCIDRを指定するとエラーになる", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", CIDR: []string{"192.168.1.0/24", "192.168.1.128/25"}, MgwResourceID: "aaaaaaa", Output: "text"}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("モバイルゲートウェイのリソースIDと名前を同時に指定するとエラーになる", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", CIDR: []string{"192.168.1.0/29"}, MgwResourceID: "aaaaaaa", MgwName: "mgw01", Output: "text"}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("モバイルゲートウェイの名前のみの指定は正しい", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", CIDR: []string{"192.168.1.0/29"}, MgwName: "mgw01", Output: "text"}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		t.Log("OK")
+	})
 }
@@ -5,6 +5,7 @@ go 1.21.4
 require (
 	github.com/jessevdk/go-flags v1.5.0
 	golang.org/x/exp v0.0.0-20240213143201-ec583247a57a
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require golang.org/x/sys v0.0.0-20210320140829-1e4c9ba3b0c4 // indirect
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	CsvPath           string `long:"csv" description:"CSVファイルのパス"`
+	AccessToken       string `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Clear             bool   `long:"clear" description:"CSVファイルのIMEIに関わらず、IMEIロックを解除する"`
+}
+
+// コマンドライン引数のバリデーションを行う
+func validateArgs(opts Options) error {
+	if opts.CsvPath == "" {
+		return errors.New("コマンドライン引数にCSVファイルのパスを指定してください")
+	}
+
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	return nil
+}
+
+// ICCID, IMEI の CSV ファイルを読み込む
+// IMEI が空の行と、clear が true の場合は IMEI ロックの解除になる
+func loadIMEILockCsv(csvPath string, clear bool) ([]common.IMEILockInfo, error) {
+	list := make([]common.IMEILockInfo, 0, 100)
+
+	// CSVファイルを開く
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("CSVファイルのオープンに失敗しました...%s", err.Error())
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	// IMEIの列は省略できるので、列数は行ごとに異なっていても良い
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// ファイルの末尾に到達
+				break
+			}
+			return nil, fmt.Errorf("読み込みに失敗しました...%s", err.Error())
+		}
+		lineNo, _ := reader.FieldPos(0)
+		if len(record) > 2 {
+			return nil, fmt.Errorf("%d行目:列数が正しくありません...1列または2列必要ですが%d列読み込みました", lineNo, len(record))
+		}
+
+		info := common.IMEILockInfo{ICCID: strings.TrimSpace(record[0])}
+		if info.ICCID == "" {
+			return nil, fmt.Errorf("%d行目:ICCIDが空です", lineNo)
+		}
+		if len(record) == 2 && !clear {
+			info.IMEI = strings.TrimSpace(record[1])
+		}
+		if info.IMEI != "" {
+			err = common.ValidateIMEI(info.IMEI)
+			if err != nil {
+				return nil, fmt.Errorf("%d行目:%s", lineNo, err.Error())
+			}
+		}
+		list = append(list, info)
+	}
+
+	return list, nil
+}
+
+func main() {
+	// コマンドライン引数の確認
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	err = validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// CSVの読み込み
+	fmt.Printf("CSVファイル(%s)の読み込み中...", opts.CsvPath)
+	list, err := loadIMEILockCsv(opts.CsvPath, opts.Clear)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+
+	fmt.Println("IMEIロック一括設定 開始")
+	err = common.SetIMEILockFromList(opts.AccessToken, opts.AccessTokenSecret, list)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("IMEIロック一括設定 完了")
+
+	os.Exit(0)
+}
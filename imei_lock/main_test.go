@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+func TestLoadIMEILockCsv(t *testing.T) {
+	t.Run("IMEIが空の行はIMEIロックの解除になる", func(t *testing.T) {
+		list, err := loadIMEILockCsv("testdata/load_test.csv", false)
+		if err != nil {
+			t.Fatalf("CSVファイルが読み込めません。%s", err.Error())
+		}
+
+		expected := []common.IMEILockInfo{
+			{ICCID: "8981040000000123400", IMEI: "350000000000000"},
+			{ICCID: "8981040000000123401", IMEI: "350000000000001"},
+			{ICCID: "8981040000000123402", IMEI: ""},
+			{ICCID: "8981040000000123403", IMEI: ""},
+		}
+		if !reflect.DeepEqual(expected, list) {
+			t.Fatalf("list expected...%v, got ...%v\n", expected, list)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("clearを指定すると全てIMEIロックの解除になる", func(t *testing.T) {
+		list, err := loadIMEILockCsv("testdata/load_test.csv", true)
+		if err != nil {
+			t.Fatalf("CSVファイルが読み込めません。%s", err.Error())
+		}
+
+		for _, info := range list {
+			if info.IMEI != "" {
+				t.Fatalf("empty IMEI is expected, got ...%v\n", info)
+			}
+		}
+		t.Log("OK")
+	})
+
+	t.Run("不正なIMEIがあるとエラーになる", func(t *testing.T) {
+		_, err := loadIMEILockCsv("testdata/invalid_imei.csv", false)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestValidateIMEI(t *testing.T) {
+	t.Run("15桁の数字以外はエラーになる", func(t *testing.T) {
+		for _, imei := range []string{"35000000000000", "3500000000000000", "35000000000000a"} {
+			if common.ValidateIMEI(imei) == nil {
+				t.Fatalf("error is expected for %s", imei)
+			}
+		}
+		if err := common.ValidateIMEI("350000000000000"); err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		t.Log("OK")
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("CSVファイルのパスが無いとエラーになる", func(t *testing.T) {
+		options := Options{CsvPath: "", AccessToken: "Token", AccessTokenSecret: "Secret"}
+		err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	AccessToken       string `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Zone              string `long:"zone" description:"さくらのクラウドゾーン"`
+	MgwResourceID     string `long:"mgw-resource-id" description:"モバイルゲートウェイのリソースID"`
+	MgwName           string `long:"mgw-name" description:"モバイルゲートウェイの名前(リソースIDの代わりに指定)"`
+	Output            string `long:"output" default:"text" description:"diff の出力形式(text, json)"`
+	Args              struct {
+		Action string   `positional-arg-name:"snapshot|diff"`
+		Files  []string `positional-arg-name:"snapshot-files"`
+	} `positional-args:"yes"`
+}
+
+// モバイルゲートウェイの SIM の一覧のスナップショット
+type Snapshot struct {
+	TakenAt       string        `json:"taken_at"`
+	Zone          string        `json:"zone"`
+	MgwResourceID string        `json:"mgw_resource_id"`
+	MgwName       string        `json:"mgw_name"`
+	Sims          []SnapshotSim `json:"sims"`
+}
+
+// スナップショットの SIM
+type SnapshotSim struct {
+	ICCID         string   `json:"iccid"`
+	ResourceID    string   `json:"resource_id"`
+	Name          string   `json:"name"`
+	IP            string   `json:"ip"`
+	Activated     bool     `json:"activated"`
+	IMEILock      bool     `json:"imei_lock"`
+	SessionStatus string   `json:"session_status"`
+	Tags          []string `json:"tags"`
+}
+
+// IP アドレスが変わった SIM
+type ReaddressedSim struct {
+	ICCID string `json:"iccid"`
+	OldIP string `json:"old_ip"`
+	NewIP string `json:"new_ip"`
+}
+
+// 2つのスナップショットの差分
+type SnapshotDiff struct {
+	Added       []SnapshotSim    `json:"added"`
+	Removed     []SnapshotSim    `json:"removed"`
+	Readdressed []ReaddressedSim `json:"readdressed"`
+}
+
+// コマンドライン引数のバリデーションを行う
+func validateArgs(opts Options) error {
+	switch opts.Args.Action {
+	case "snapshot":
+		if len(opts.Args.Files) > 0 {
+			return errors.New("snapshot にはファイルを指定できません。結果は標準出力に出力します")
+		}
+		if (opts.MgwResourceID == "") == (opts.MgwName == "") {
+			return errors.New("コマンドライン引数にモバイルゲートウェイのリソースIDか名前のいずれかを指定してください")
+		}
+		if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+			return errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+		}
+		return common.ValidateZone(opts.Zone)
+	case "diff":
+		if len(opts.Args.Files) != 2 {
+			return errors.New("diff には比較する2つのスナップショットのファイルを指定してください")
+		}
+		return common.ValidateOutput(opts.Output, []string{"text", "json"})
+	}
+	return errors.New("snapshot か diff のいずれかを指定してください")
+}
+
+// モバイルゲートウェイの SIM とアカウント内の SIM の情報からスナップショットを作成する
+func buildSnapshot(takenAt time.Time, zone string, mgw common.Mgw, sims []common.MgwSim, accountSims []common.AccountSim) Snapshot {
+	accountSimByICCID := make(map[string]common.AccountSim)
+	for _, sim := range accountSims {
+		accountSimByICCID[sim.Status.ICCID] = sim
+	}
+
+	snapshot := Snapshot{
+		TakenAt:       takenAt.Format(time.RFC3339),
+		Zone:          zone,
+		MgwResourceID: mgw.ID,
+		MgwName:       mgw.Name,
+		Sims:          make([]SnapshotSim, 0, len(sims)),
+	}
+	for _, sim := range sims {
+		accountSim := accountSimByICCID[sim.ICCID]
+		tags := accountSim.Tags
+		if tags == nil {
+			tags = make([]string, 0)
+		}
+		snapshot.Sims = append(snapshot.Sims, SnapshotSim{
+			ICCID:         sim.ICCID,
+			ResourceID:    sim.ResourceID,
+			Name:          accountSim.Name,
+			IP:            sim.IP,
+			Activated:     sim.Activated,
+			IMEILock:      sim.IMEILock,
+			SessionStatus: sim.SessionStatus,
+			Tags:          tags,
+		})
+	}
+	// 差分を取りやすいように ICCID 順に並べる
+	slices.SortFunc(snapshot.Sims, func(a, b SnapshotSim) int { return strings.Compare(a.ICCID, b.ICCID) })
+	return snapshot
+}
+
+// スナップショットを取得する
+func takeSnapshot(opts Options) (Snapshot, error) {
+	mgwID := opts.MgwResourceID
+	if opts.MgwName != "" {
+		var err error
+		mgwID, err = common.ResolveMgwID(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwName)
+		if err != nil {
+			return Snapshot{}, err
+		}
+	}
+
+	mgw, err := common.GetMgw(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, mgwID)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	sims, err := common.GetSimsInMGW(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, mgwID)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	// 名前とタグはアカウント内の SIM の情報にしかない
+	accountSims, err := common.GetSimsInAccount(opts.AccessToken, opts.AccessTokenSecret)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return buildSnapshot(time.Now(), opts.Zone, mgw, sims, accountSims), nil
+}
+
+// スナップショットのファイルを読み込む
+func loadSnapshot(path string) (Snapshot, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("スナップショットの読み込みに失敗しました...%s", err.Error())
+	}
+
+	var snapshot Snapshot
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&snapshot)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("スナップショットのパースに失敗しました(%s)...%s", path, err.Error())
+	}
+	return snapshot, nil
+}
+
+// 2つのスナップショットの差分を ICCID 順に返す
+func diffSnapshots(before Snapshot, after Snapshot) SnapshotDiff {
+	beforeByICCID := make(map[string]SnapshotSim)
+	for _, sim := range before.Sims {
+		beforeByICCID[sim.ICCID] = sim
+	}
+	afterByICCID := make(map[string]SnapshotSim)
+	for _, sim := range after.Sims {
+		afterByICCID[sim.ICCID] = sim
+	}
+
+	diff := SnapshotDiff{
+		Added:       make([]SnapshotSim, 0),
+		Removed:     make([]SnapshotSim, 0),
+		Readdressed: make([]ReaddressedSim, 0),
+	}
+	for _, sim := range after.Sims {
+		old, exists := beforeByICCID[sim.ICCID]
+		if !exists {
+			diff.Added = append(diff.Added, sim)
+			continue
+		}
+		if old.IP != sim.IP {
+			diff.Readdressed = append(diff.Readdressed, ReaddressedSim{ICCID: sim.ICCID, OldIP: old.IP, NewIP: sim.IP})
+		}
+	}
+	for _, sim := range before.Sims {
+		if _, exists := afterByICCID[sim.ICCID]; !exists {
+			diff.Removed = append(diff.Removed, sim)
+		}
+	}
+
+	slices.SortFunc(diff.Added, func(a, b SnapshotSim) int { return strings.Compare(a.ICCID, b.ICCID) })
+	slices.SortFunc(diff.Removed, func(a, b SnapshotSim) int { return strings.Compare(a.ICCID, b.ICCID) })
+	slices.SortFunc(diff.Readdressed, func(a, b ReaddressedSim) int { return strings.Compare(a.ICCID, b.ICCID) })
+	return diff
+}
+
+// 差分を指定された形式で出力する
+func writeDiff(w io.Writer, output string, before Snapshot, after Snapshot, diff SnapshotDiff) error {
+	if output == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	}
+
+	lines := []string{fmt.Sprintf("%s -> %s", before.TakenAt, after.TakenAt)}
+	for _, sim := range diff.Added {
+		lines = append(lines, fmt.Sprintf("+ %s\t%s", sim.ICCID, sim.IP))
+	}
+	for _, sim := range diff.Removed {
+		lines = append(lines, fmt.Sprintf("- %s\t%s", sim.ICCID, sim.IP))
+	}
+	for _, sim := range diff.Readdressed {
+		lines = append(lines, fmt.Sprintf("~ %s\t%s -> %s", sim.ICCID, sim.OldIP, sim.NewIP))
+	}
+	lines = append(lines, fmt.Sprintf("追加: %d, 削除: %d, IPアドレス変更: %d", len(diff.Added), len(diff.Removed), len(diff.Readdressed)))
+
+	for _, line := range lines {
+		_, err := fmt.Fprintln(w, line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main() {
+	// コマンドラインオプションのパース
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// コマンドライン引数を バリデーションする
+	err = validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if opts.Args.Action == "snapshot" {
+		// 結果をパイプで渡せるように、標準エラー出力に出す
+		fmt.Fprintln(os.Stderr, "情報を取得しています...")
+		snapshot, err := takeSnapshot(opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(snapshot)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "結果の出力に失敗しました...%s\n", err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	before, err := loadSnapshot(opts.Args.Files[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	after, err := loadSnapshot(opts.Args.Files[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	if before.MgwResourceID != after.MgwResourceID {
+		// 別のモバイルゲートウェイ同士の比較もできるが、間違いの可能性が高いので警告する
+		fmt.Fprintf(os.Stderr, "警告: 異なるモバイルゲートウェイのスナップショットです(%s, %s)\n", before.MgwResourceID, after.MgwResourceID)
+	}
+
+	err = writeDiff(os.Stdout, opts.Output, before, after, diffSnapshots(before, after))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "結果の出力に失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+func TestBuildSnapshot(t *testing.T) {
+	t.Run("アカウント内のSIMの名前とタグを含めてICCID順に並べる", func(t *testing.T) {
+		mgw := common.Mgw{ID: "113000000100", Name: "mgw01"}
+		sims := []common.MgwSim{
+			{ICCID: "8981040000000123401", ResourceID: "113000000001", IP: "192.168.1.2"},
+			{ICCID: "8981040000000123400", ResourceID: "113000000000", IP: "192.168.1.1", Activated: true},
+		}
+		accountSims := make([]common.AccountSim, 1)
+		accountSims[0].ID = "113000000000"
+		accountSims[0].Name = "site-a-01"
+		accountSims[0].Tags = []string{"site-a"}
+		accountSims[0].Status.ICCID = "8981040000000123400"
+
+		takenAt := time.Date(2024, 4, 1, 9, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+		snapshot := buildSnapshot(takenAt, "is1b", mgw, sims, accountSims)
+
+		expected := Snapshot{
+			TakenAt:       "2024-04-01T09:00:00+09:00",
+			Zone:          "is1b",
+			MgwResourceID: "113000000100",
+			MgwName:       "mgw01",
+			Sims: []SnapshotSim{
+				{ICCID: "8981040000000123400", ResourceID: "113000000000", Name: "site-a-01", IP: "192.168.1.1", Activated: true, Tags: []string{"site-a"}},
+				{ICCID: "8981040000000123401", ResourceID: "113000000001", IP: "192.168.1.2", Tags: []string{}},
+			},
+		}
+		if !reflect.DeepEqual(expected, snapshot) {
+			t.Fatalf("snapshot expected...%v, got ...%v\n", expected, snapshot)
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	before, err := loadSnapshot("testdata/snapshot_before.json")
+	if err != nil {
+		t.Fatalf("nil error is expected, but got %s", err.Error())
+	}
+	after, err := loadSnapshot("testdata/snapshot_after.json")
+	if err != nil {
+		t.Fatalf("nil error is expected, but got %s", err.Error())
+	}
+
+	t.Run("追加、削除、IPアドレスが変わったSIMを返す", func(t *testing.T) {
+		diff := diffSnapshots(before, after)
+		if len(diff.Added) != 1 || diff.Added[0].ICCID != "8981040000000123402" {
+			t.Fatalf("unexpected added...%v", diff.Added)
+		}
+		if len(diff.Removed) != 1 || diff.Removed[0].ICCID != "8981040000000123400" {
+			t.Fatalf("unexpected removed...%v", diff.Removed)
+		}
+		expected := []ReaddressedSim{{ICCID: "8981040000000123401", OldIP: "192.168.1.2", NewIP: "10.0.0.2"}}
+		if !reflect.DeepEqual(expected, diff.Readdressed) {
+			t.Fatalf("readdressed expected...%v, got ...%v\n", expected, diff.Readdressed)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("テキスト形式で出力する", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeDiff(&buf, "text", before, after, diffSnapshots(before, after))
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := "2024-04-01T09:00:00+09:00 -> 2024-05-01T09:00:00+09:00\n" +
+			"+ 8981040000000123402\t192.168.1.3\n" +
+			"- 8981040000000123400\t192.168.1.1\n" +
+			"~ 8981040000000123401\t192.168.1.2 -> 10.0.0.2\n" +
+			"追加: 1, 削除: 1, IPアドレス変更: 1\n"
+		if buf.String() != expected {
+			t.Fatalf("output expected...%s, got ...%s\n", expected, buf.String())
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("diffに比較するファイルが2つ無いとエラーになる", func(t *testing.T) {
+		options := Options{Output: "text"}
+		options.Args.Action = "diff"
+		options.Args.Files = []string{"testdata/snapshot_before.json"}
+		err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
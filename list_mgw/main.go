@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	AccessToken       string   `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string   `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Zone              []string `long:"zone" description:"さくらのクラウドゾーン(複数指定可、省略時はすべてのゾーン)"`
+	Output            string   `long:"output" default:"table" description:"出力形式(table, json, csv)"`
+}
+
+// モバイルゲートウェイの一覧に出力する情報
+type MgwSummary struct {
+	Zone     string   `json:"zone"`
+	ID       string   `json:"resource_id"`
+	Name     string   `json:"name"`
+	Tags     []string `json:"tags"`
+	Status   string   `json:"status"`
+	SimCount int      `json:"sim_count"`
+}
+
+// コマンドライン引数のバリデーションを行い、対象のゾーンを返す
+func validateArgs(opts Options) ([]string, error) {
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return nil, errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	err := common.ValidateOutput(opts.Output, []string{"table", "json", "csv"})
+	if err != nil {
+		return nil, err
+	}
+
+	// 省略時はすべてのゾーンを対象とする
+	if len(opts.Zone) == 0 {
+		return common.ValidZones, nil
+	}
+
+	zones := make([]string, 0, len(opts.Zone))
+	for _, zone := range opts.Zone {
+		err = common.ValidateZone(zone)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(zones, zone) {
+			zones = append(zones, zone)
+		}
+	}
+	return zones, nil
+}
+
+// モバイルゲートウェイの一覧を指定された形式で出力する
+func writeMgws(w io.Writer, output string, mgws []MgwSummary) error {
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(mgws)
+	case "csv":
+		writer := csv.NewWriter(w)
+		err := writer.Write([]string{"zone", "resource_id", "name", "tags", "status", "sim_count"})
+		if err != nil {
+			return err
+		}
+		for _, mgw := range mgws {
+			err = writer.Write([]string{
+				mgw.Zone,
+				mgw.ID,
+				mgw.Name,
+				strings.Join(mgw.Tags, " "),
+				mgw.Status,
+				strconv.Itoa(mgw.SimCount),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, err := fmt.Fprintln(writer, "ZONE\tRESOURCE_ID\tNAME\tTAGS\tSTATUS\tSIMS")
+		if err != nil {
+			return err
+		}
+		for _, mgw := range mgws {
+			_, err = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%d\n",
+				mgw.Zone, mgw.ID, mgw.Name, strings.Join(mgw.Tags, ","), mgw.Status, mgw.SimCount)
+			if err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+	}
+}
+
+// ゾーン内のモバイルゲートウェイと、それぞれに登録されている SIM の数を取得する
+func getMgwSummaries(accessToken string, accessTokenSecret string, zone string) ([]MgwSummary, error) {
+	mgws, err := common.GetMgwsInZone(accessToken, accessTokenSecret, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]MgwSummary, 0, len(mgws))
+	for _, mgw := range mgws {
+		sims, err := common.GetSimsInMGW(accessToken, accessTokenSecret, zone, mgw.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		tags := mgw.Tags
+		if tags == nil {
+			tags = make([]string, 0)
+		}
+		summaries = append(summaries, MgwSummary{
+			Zone:     zone,
+			ID:       mgw.ID,
+			Name:     mgw.Name,
+			Tags:     tags,
+			Status:   mgw.Instance.Status,
+			SimCount: len(sims),
+		})
+	}
+	return summaries, nil
+}
+
+func main() {
+	// コマンドラインオプションのパース
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// コマンドライン引数を バリデーションする
+	zones, err := validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// 結果をパイプで渡せるように、標準エラー出力に出す
+	fmt.Fprintln(os.Stderr, "情報を取得しています...")
+
+	mgws := make([]MgwSummary, 0)
+	for _, zone := range zones {
+		summaries, err := getMgwSummaries(opts.AccessToken, opts.AccessTokenSecret, zone)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", zone, err.Error())
+			os.Exit(1)
+		}
+		mgws = append(mgws, summaries...)
+	}
+
+	// モバイルゲートウェイの一覧を表示する
+	err = writeMgws(os.Stdout, opts.Output, mgws)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "結果の出力に失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
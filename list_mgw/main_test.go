@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// テストに使用するモバイルゲートウェイ一覧
+var testMgws = []MgwSummary{
+	{Zone: "is1b", ID: "113000000000", Name: "mgw01", Tags: []string{"prod", "site-a"}, Status: "up", SimCount: 12},
+	{Zone: "tk1b", ID: "113000000001", Name: "mgw02", Tags: []string{}, Status: "down", SimCount: 0},
+}
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("ゾーンを省略するとすべてのゾーンが対象になる", func(t *testing.T) {
+		zones, err := validateArgs(Options{AccessToken: "Token", AccessTokenSecret: "Secret", Output: "table"})
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if !reflect.DeepEqual(common.ValidZones, zones) {
+			t.Fatalf("zones expected...%v, got ...%v\n", common.ValidZones, zones)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("同じゾーンを複数回指定しても1回だけ対象になる", func(t *testing.T) {
+		zones, err := validateArgs(Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: []string{"is1b", "is1b"}, Output: "table"})
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if !reflect.DeepEqual([]string{"is1b"}, zones) {
+			t.Fatalf("unexpected zones...%v", zones)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("不正なゾーンを指定するとエラーになる", func(t *testing.T) {
+		_, err := validateArgs(Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: []string{"is1c"}, Output: "table"})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestWriteMgws(t *testing.T) {
+	t.Run("CSV形式で出力する", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeMgws(&buf, "csv", testMgws)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := "zone,resource_id,name,tags,status,sim_count\n" +
+			"is1b,113000000000,mgw01,prod site-a,up,12\n" +
+			"tk1b,113000000001,mgw02,,down,0\n"
+		if buf.String() != expected {
+			t.Fatalf("output expected...%s, got ...%s\n", expected, buf.String())
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestFindMgwIDByName(t *testing.T) {
+	mgws := []common.Mgw{
+		{ID: "113000000000", Name: "mgw01"},
+		{ID: "113000000001", Name: "mgw02"},
+		{ID: "113000000002", Name: "mgw02"},
+	}
+
+	t.Run("名前が一致するモバイルゲートウェイのリソースIDを返す", func(t *testing.T) {
+		id, err := common.FindMgwIDByName(mgws, "mgw01")
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if id != "113000000000" {
+			t.Fatalf("unexpected id...%s", id)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("名前が一致するモバイルゲートウェイが無いとエラーになる", func(t *testing.T) {
+		_, err := common.FindMgwIDByName(mgws, "mgw")
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("名前が一致するモバイルゲートウェイが複数あるとエラーになる", func(t *testing.T) {
+		_, err := common.FindMgwIDByName(mgws, "mgw02")
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
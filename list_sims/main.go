@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	AccessToken       string   `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string   `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Zone              string   `long:"zone" description:"さくらのクラウドゾーン"`
+	MgwResourceID     string   `long:"mgw-resource-id" description:"モバイルゲートウェイのリソースID"`
+	Output            string   `long:"output" default:"table" description:"出力形式(table, json, csv)"`
+	State             string   `long:"state" description:"SIMの状態で絞り込む(activated, deactivated)"`
+	Session           string   `long:"session" description:"SIMのセッション状態で絞り込む(up, down)"`
+	CIDR              []string `long:"cidr" description:"IPアドレスの範囲で絞り込む(複数指定可)"`
+}
+
+// SIM 一覧の絞り込み条件
+type Filter struct {
+	State   string
+	Session string
+	IPNets  []*net.IPNet
+}
+
+// コマンドライン引数のバリデーションを行い、絞り込み条件を返す
+func validateArgs(opts Options) (Filter, error) {
+	if opts.MgwResourceID == "" {
+		return Filter{}, errors.New("コマンドライン引数にモバイルゲートウェイのリソースIDを指定してください")
+	}
+
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return Filter{}, errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	err := common.ValidateZone(opts.Zone)
+	if err != nil {
+		return Filter{}, err
+	}
+
+	err = common.ValidateOutput(opts.Output, []string{"table", "json", "csv"})
+	if err != nil {
+		return Filter{}, err
+	}
+
+	if opts.State != "" && !slices.Contains([]string{"activated", "deactivated"}, opts.State) {
+		return Filter{}, errors.New("不正なSIMの状態です。activated, deactivated から指定してください")
+	}
+
+	if opts.Session != "" && !slices.Contains([]string{"up", "down"}, opts.Session) {
+		return Filter{}, errors.New("不正なセッション状態です。up, down から指定してください")
+	}
+
+	filter := Filter{State: opts.State, Session: opts.Session, IPNets: make([]*net.IPNet, 0, len(opts.CIDR))}
+	for _, cidr := range opts.CIDR {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return Filter{}, fmt.Errorf("正しいフォーマットのCIDRを指定してください: %s", err.Error())
+		}
+		filter.IPNets = append(filter.IPNets, ipNet)
+	}
+
+	return filter, nil
+}
+
+// 絞り込み条件に一致する SIM のみを返す
+func filterSims(filter Filter, sims []common.MgwSim) []common.MgwSim {
+	filtered := make([]common.MgwSim, 0, len(sims))
+	for _, sim := range sims {
+		if filter.State == "activated" && !sim.Activated {
+			continue
+		}
+		if filter.State == "deactivated" && sim.Activated {
+			continue
+		}
+		if filter.Session != "" && !strings.EqualFold(sim.SessionStatus, filter.Session) {
+			continue
+		}
+		if len(filter.IPNets) > 0 {
+			ip := net.ParseIP(sim.IP)
+			if ip == nil {
+				continue
+			}
+			if !slices.ContainsFunc(filter.IPNets, func(ipNet *net.IPNet) bool { return ipNet.Contains(ip) }) {
+				continue
+			}
+		}
+		filtered = append(filtered, sim)
+	}
+	return filtered
+}
+
+// SIM 一覧を指定された形式で出力する
+func writeSims(w io.Writer, output string, sims []common.MgwSim) error {
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(sims)
+	case "csv":
+		writer := csv.NewWriter(w)
+		err := writer.Write([]string{"iccid", "resource_id", "ip", "activated", "imei_lock", "session_status"})
+		if err != nil {
+			return err
+		}
+		for _, sim := range sims {
+			err = writer.Write([]string{
+				sim.ICCID,
+				sim.ResourceID,
+				sim.IP,
+				strconv.FormatBool(sim.Activated),
+				strconv.FormatBool(sim.IMEILock),
+				sim.SessionStatus,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, err := fmt.Fprintln(writer, "ICCID\tRESOURCE_ID\tIP\tACTIVATED\tIMEI_LOCK\tSESSION")
+		if err != nil {
+			return err
+		}
+		for _, sim := range sims {
+			_, err = fmt.Fprintf(writer, "%s\t%s\t%s\t%t\t%t\t%s\n",
+				sim.ICCID, sim.ResourceID, sim.IP, sim.Activated, sim.IMEILock, sim.SessionStatus)
+			if err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+	}
+}
+
+func main() {
+	// コマンドラインオプションのパース
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// コマンドライン引数を バリデーションする
+	filter, err := validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// 結果をパイプで渡せるように、標準エラー出力に出す
+	fmt.Fprintln(os.Stderr, "情報を取得しています...")
+
+	sims, err := common.GetSimsInMGW(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwResourceID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	// SIM一覧を表示する
+	err = writeSims(os.Stdout, opts.Output, filterSims(filter, sims))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "結果の出力に失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
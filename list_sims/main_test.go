@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// テストに使用する SIM 一覧
+var testSims = []common.MgwSim{
+	{ICCID: "8981040000000123400", ResourceID: "113000000000", IP: "192.168.1.1", Activated: true, IMEILock: true, SessionStatus: "UP"},
+	{ICCID: "8981040000000123401", ResourceID: "113000000001", IP: "192.168.1.2", Activated: true, IMEILock: false, SessionStatus: "DOWN"},
+	{ICCID: "8981040000000123402", ResourceID: "113000000002", IP: "192.168.2.1", Activated: false, IMEILock: false, SessionStatus: "DOWN"},
+	{ICCID: "8981040000000123403", ResourceID: "113000000003", IP: "", Activated: false, IMEILock: false, SessionStatus: "DOWN"},
+}
+
+func TestFilterSims(t *testing.T) {
+	t.Run("絞り込み条件が無ければ全件返す", func(t *testing.T) {
+		actual := filterSims(Filter{}, testSims)
+		if !reflect.DeepEqual(testSims, actual) {
+			t.Fatalf("sims expected...%v, got ...%v\n", testSims, actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("SIMの状態で絞り込む", func(t *testing.T) {
+		actual := filterSims(Filter{State: "deactivated"}, testSims)
+		if len(actual) != 2 || actual[0].ICCID != "8981040000000123402" {
+			t.Fatalf("unexpected sims...%v\n", actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("セッション状態で絞り込む", func(t *testing.T) {
+		actual := filterSims(Filter{Session: "up"}, testSims)
+		if len(actual) != 1 || actual[0].ICCID != "8981040000000123400" {
+			t.Fatalf("unexpected sims...%v\n", actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("IPアドレスの範囲で絞り込む", func(t *testing.T) {
+		_, ipNet, _ := net.ParseCIDR("192.168.1.0/24")
+		actual := filterSims(Filter{IPNets: []*net.IPNet{ipNet}, State: "activated"}, testSims)
+		if len(actual) != 2 || actual[1].ICCID != "8981040000000123401" {
+			t.Fatalf("unexpected sims...%v\n", actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestWriteSims(t *testing.T) {
+	t.Run("csv形式ではヘッダ付きで出力する", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeSims(&buf, "csv", testSims[:1])
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+
+		expected := "iccid,resource_id,ip,activated,imei_lock,session_status\n8981040000000123400,113000000000,192.168.1.1,true,true,UP\n"
+		if buf.String() != expected {
+			t.Fatalf("output expected...%q, got ...%q\n", expected, buf.String())
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("table形式ではヘッダと全件を出力する", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeSims(&buf, "table", testSims)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != len(testSims)+1 || !strings.HasPrefix(lines[0], "ICCID") {
+			t.Fatalf("unexpected table output...%q\n", buf.String())
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestValidateZone(t *testing.T) {
+	t.Run("不正なゾーンを入力したら、エラーが返る", func(t *testing.T) {
+		zone := "tk3a"
+		err := common.ValidateZone(zone)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("アクセストークン、アクセストークンシークレットが無いとエラーになる", func(t *testing.T) {
+		options := Options{AccessToken: "", AccessTokenSecret: "", Zone: "is1a", MgwResourceID: "aaaaaaa", Output: "table"}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("不正なSIMの状態を指定するとエラーになる", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", MgwResourceID: "aaaaaaa", Output: "table", State: "enabled"}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("不正なCIDRを指定するとエラーになる", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", MgwResourceID: "aaaaaaa", Output: "table", CIDR: []string{"192.168.1.0.0/24"}}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	AccessToken       string `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Zone              string `long:"zone" description:"さくらのクラウドゾーン"`
+	MgwResourceID     string `long:"mgw-resource-id" description:"モバイルゲートウェイのリソースID"`
+	MgwName           string `long:"mgw-name" description:"モバイルゲートウェイの名前(リソースIDの代わりに指定)"`
+	ApplyPath         string `long:"apply" description:"設定ファイルのパス。省略時は現在の設定を出力する"`
+	DryRun            bool   `long:"dry-run" description:"APIを呼び出さずに変更内容のみ表示する"`
+}
+
+// コマンドライン引数のバリデーションを行う
+func validateArgs(opts Options) error {
+	if (opts.MgwResourceID == "") == (opts.MgwName == "") {
+		return errors.New("コマンドライン引数にモバイルゲートウェイのリソースIDか名前のいずれかを指定してください")
+	}
+
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	err := common.ValidateZone(opts.Zone)
+	if err != nil {
+		return err
+	}
+
+	if opts.DryRun && opts.ApplyPath == "" {
+		return errors.New("--dry-run は --apply と同時に指定してください")
+	}
+
+	return nil
+}
+
+// 設定ファイルを読み込む
+// 誤記に気付けるように、知らない項目があればエラーにする
+func loadTrafficControlFile(path string) (common.TrafficControl, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return common.TrafficControl{}, fmt.Errorf("設定ファイルの読み込みに失敗しました...%s", err.Error())
+	}
+
+	var tc common.TrafficControl
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&tc)
+	if err != nil {
+		return common.TrafficControl{}, fmt.Errorf("設定ファイルのパースに失敗しました...%s", err.Error())
+	}
+
+	err = common.ValidateTrafficControl(tc)
+	if err != nil {
+		return common.TrafficControl{}, fmt.Errorf("設定ファイルが不正です...%s", err.Error())
+	}
+	return tc, nil
+}
+
+// 現在の設定と設定ファイルの差分を "項目: 変更前 -> 変更後" の形式で返す
+func diffTrafficControl(current common.TrafficControl, desired common.TrafficControl) []string {
+	// 無効にする場合は、他の項目は変更されない
+	if !desired.Enabled {
+		if current.Enabled {
+			return []string{"enabled: true -> false"}
+		}
+		return []string{}
+	}
+
+	fields := []struct {
+		Name    string
+		Current any
+		Desired any
+	}{
+		{"enabled", current.Enabled, desired.Enabled},
+		{"traffic_quota_in_mb", current.TrafficQuotaInMB, desired.TrafficQuotaInMB},
+		{"bandwidth_limit_in_kbps", current.BandWidthLimitInKbps, desired.BandWidthLimitInKbps},
+		{"auto_traffic_shaping", current.AutoTrafficShaping, desired.AutoTrafficShaping},
+		{"email_notification", current.EmailNotification, desired.EmailNotification},
+		{"slack_notification", current.SlackNotification, desired.SlackNotification},
+		{"slack_url", current.SlackURL, desired.SlackURL},
+	}
+	diffs := make([]string, 0)
+	for _, field := range fields {
+		if field.Current != field.Desired {
+			diffs = append(diffs, fmt.Sprintf("%s: %v -> %v", field.Name, field.Current, field.Desired))
+		}
+	}
+	return diffs
+}
+
+// 設定を設定ファイルと同じ形式で出力する
+func writeTrafficControl(w io.Writer, tc common.TrafficControl) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(tc)
+}
+
+func main() {
+	// コマンドラインオプションのパース
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// コマンドライン引数を バリデーションする
+	err = validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// 設定ファイルは API を呼び出す前に確認する
+	var desired common.TrafficControl
+	if opts.ApplyPath != "" {
+		desired, err = loadTrafficControlFile(opts.ApplyPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	}
+
+	// 結果をパイプで渡せるように、標準エラー出力に出す
+	fmt.Fprintln(os.Stderr, "情報を取得しています...")
+
+	if opts.MgwName != "" {
+		opts.MgwResourceID, err = common.ResolveMgwID(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	}
+
+	current, err := common.GetMgwTrafficControl(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwResourceID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	if opts.ApplyPath == "" {
+		// 現在の設定を設定ファイルの形式で表示する
+		err = writeTrafficControl(os.Stdout, current)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "結果の出力に失敗しました...%s\n", err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	diffs := diffTrafficControl(current, desired)
+	if len(diffs) == 0 {
+		fmt.Println("トラフィックコントロールの設定に変更はありません")
+		os.Exit(0)
+	}
+	fmt.Println("トラフィックコントロールの変更内容")
+	for _, diff := range diffs {
+		fmt.Printf("  %s\n", diff)
+	}
+	if opts.DryRun {
+		os.Exit(0)
+	}
+
+	fmt.Printf("トラフィックコントロールの設定中...")
+	err = common.SetMgwTrafficControl(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwResourceID, desired)
+	if err != nil {
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+
+	os.Exit(0)
+}
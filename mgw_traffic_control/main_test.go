@@ -0,0 +1,77 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+func TestLoadTrafficControlFile(t *testing.T) {
+	t.Run("設定ファイルを読み込む", func(t *testing.T) {
+		tc, err := loadTrafficControlFile("testdata/traffic_control.json")
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := common.TrafficControl{Enabled: true, TrafficQuotaInMB: 10240, BandWidthLimitInKbps: 128, AutoTrafficShaping: true, EmailNotification: true}
+		if !reflect.DeepEqual(expected, tc) {
+			t.Fatalf("config expected...%v, got ...%v\n", expected, tc)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("知らない項目があるとエラーになる", func(t *testing.T) {
+		_, err := loadTrafficControlFile("testdata/unknown_field.json")
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestValidateTrafficControl(t *testing.T) {
+	t.Run("Slack通知を有効にしてURLが無いとエラーになる", func(t *testing.T) {
+		err := common.ValidateTrafficControl(common.TrafficControl{Enabled: true, TrafficQuotaInMB: 1024, SlackNotification: true})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("無効にする場合は他の項目をチェックしない", func(t *testing.T) {
+		err := common.ValidateTrafficControl(common.TrafficControl{Enabled: false})
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		t.Log("OK")
+	})
+}
+
+func TestDiffTrafficControl(t *testing.T) {
+	current := common.TrafficControl{Enabled: true, TrafficQuotaInMB: 1024, BandWidthLimitInKbps: 128, EmailNotification: true}
+
+	t.Run("変更された項目のみ返す", func(t *testing.T) {
+		desired := current
+		desired.TrafficQuotaInMB = 2048
+		desired.EmailNotification = false
+
+		expected := []string{"traffic_quota_in_mb: 1024 -> 2048", "email_notification: true -> false"}
+		actual := diffTrafficControl(current, desired)
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("diffs expected...%v, got ...%v\n", expected, actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("無効にする場合は他の項目の差分を返さない", func(t *testing.T) {
+		actual := diffTrafficControl(current, common.TrafficControl{Enabled: false})
+		if !reflect.DeepEqual([]string{"enabled: true -> false"}, actual) {
+			t.Fatalf("unexpected diffs...%v", actual)
+		}
+		t.Log("OK")
+	})
+}
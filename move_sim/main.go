@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	AccessToken         string   `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret   string   `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	SourceZone          string   `long:"source-zone" description:"移動元のモバイルゲートウェイのさくらのクラウドゾーン"`
+	SourceMgwResourceID string   `long:"source-mgw-resource-id" description:"移動元のモバイルゲートウェイのリソースID"`
+	DestZone            string   `long:"dest-zone" description:"移動先のモバイルゲートウェイのさくらのクラウドゾーン"`
+	DestMgwResourceID   string   `long:"dest-mgw-resource-id" description:"移動先のモバイルゲートウェイのリソースID"`
+	CIDR                []string `long:"cidr" description:"移動前のIPアドレスが使えない場合に割り当てるCIDR(複数指定可)"`
+	CsvPath             string   `long:"csv" description:"移動対象のSIMを記載したCSVファイルのパス(1列目のICCIDのみ参照)"`
+	ICCID               []string `long:"iccid" description:"移動対象のSIMのICCID(複数指定可)"`
+	All                 bool     `long:"all" description:"移動元のモバイルゲートウェイのすべてのSIMを移動する"`
+	DryRun              bool     `long:"dry-run" description:"APIを呼び出さずに移動内容のみ表示する"`
+}
+
+// コマンドライン引数のバリデーションを行い、IP アドレスを割り当てる CIDR を返す
+func validateArgs(opts Options) ([]*net.IPNet, error) {
+	if opts.SourceMgwResourceID == "" || opts.DestMgwResourceID == "" {
+		return nil, errors.New("コマンドライン引数に移動元と移動先のモバイルゲートウェイのリソースIDを指定してください")
+	}
+
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return nil, errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	err := common.ValidateZone(opts.SourceZone)
+	if err != nil {
+		return nil, err
+	}
+	err = common.ValidateZone(opts.DestZone)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.SourceZone == opts.DestZone && opts.SourceMgwResourceID == opts.DestMgwResourceID {
+		return nil, errors.New("移動元と移動先に同じモバイルゲートウェイは指定できません")
+	}
+
+	hasList := opts.CsvPath != "" || len(opts.ICCID) > 0
+	if hasList == opts.All {
+		return nil, errors.New("コマンドライン引数にCSVファイルかICCID、または --all のいずれかを指定してください")
+	}
+
+	ipNets := make([]*net.IPNet, 0, len(opts.CIDR))
+	for _, cidr := range opts.CIDR {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("正しいフォーマットのCIDRを指定してください: %s", err.Error())
+		}
+		err = common.ValidateSimCIDR(ipNet)
+		if err != nil {
+			return nil, err
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	err = common.CheckOverlappingCIDRs(ipNets)
+	if err != nil {
+		return nil, err
+	}
+
+	return ipNets, nil
+}
+
+// 移動元のモバイルゲートウェイ配下の SIM から移動対象を選ぶ
+// iccids が空の場合はすべての SIM を対象とする
+func selectTargetSims(sims []common.MgwSim, iccids []string) ([]common.MgwSim, error) {
+	if len(iccids) == 0 {
+		return sims, nil
+	}
+
+	simByICCID := make(map[string]common.MgwSim)
+	for _, sim := range sims {
+		simByICCID[sim.ICCID] = sim
+	}
+
+	targets := make([]common.MgwSim, 0, len(iccids))
+	notFound := make([]string, 0)
+	for _, iccid := range iccids {
+		sim, exists := simByICCID[iccid]
+		if !exists {
+			notFound = append(notFound, iccid)
+			continue
+		}
+		targets = append(targets, sim)
+	}
+	if len(notFound) > 0 {
+		return nil, fmt.Errorf("移動元のモバイルゲートウェイに登録されていないSIMがあります...ICCID: %s", strings.Join(notFound, ", "))
+	}
+	return targets, nil
+}
+
+// 移動内容を作成する
+func buildEntries(opts Options, ipNets []*net.IPNet) ([]common.MoveSimEntry, error) {
+	iccids, err := common.LoadICCIDList(opts.CsvPath, opts.ICCID)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceSims, err := common.GetSimsInMGW(opts.AccessToken, opts.AccessTokenSecret, opts.SourceZone, opts.SourceMgwResourceID)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := selectTargetSims(sourceSims, iccids)
+	if err != nil {
+		return nil, err
+	}
+
+	destSims, err := common.GetSimsInMGW(opts.AccessToken, opts.AccessTokenSecret, opts.DestZone, opts.DestMgwResourceID)
+	if err != nil {
+		return nil, err
+	}
+	return common.PlanMoveSim(targets, destSims, ipNets)
+}
+
+func main() {
+	// コマンドライン引数の確認
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	ipNets, err := validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// 移動内容の作成
+	fmt.Printf("SIMの移動内容の作成中...")
+	entries, err := buildEntries(opts, ipNets)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+
+	if opts.DryRun {
+		fmt.Println("SIM一括移動 開始(ドライラン)")
+	} else {
+		fmt.Println("SIM一括移動 開始")
+	}
+	err = common.MoveSimFromList(opts.AccessToken, opts.AccessTokenSecret,
+		opts.SourceZone, opts.SourceMgwResourceID, opts.DestZone, opts.DestMgwResourceID, entries, opts.DryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("SIM一括移動 完了")
+
+	os.Exit(0)
+}
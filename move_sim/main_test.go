@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// テストに使用する移動元のモバイルゲートウェイ配下の SIM 一覧
+var testSourceSims = []common.MgwSim{
+	{ICCID: "8981040000000123400", ResourceID: "113000000000", IP: "192.168.1.1"},
+	{ICCID: "8981040000000123401", ResourceID: "113000000001", IP: "192.168.1.2"},
+}
+
+// テストに使用する移動先のモバイルゲートウェイ配下の SIM 一覧
+var testDestSims = []common.MgwSim{
+	{ICCID: "8981040000000123500", ResourceID: "113000000100", IP: "192.168.1.2"},
+}
+
+func TestPlanMoveSim(t *testing.T) {
+	t.Run("移動先で未使用のIPアドレスはそのまま使い、使用中のものはCIDRから割り当てる", func(t *testing.T) {
+		_, ipNet, _ := net.ParseCIDR("192.168.1.0/29")
+
+		entries, err := common.PlanMoveSim(testSourceSims, testDestSims, []*net.IPNet{ipNet})
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+
+		expected := []common.MoveSimEntry{
+			{ICCID: "8981040000000123400", ResourceID: "113000000000", OldIP: "192.168.1.1", NewIP: "192.168.1.1"},
+			{ICCID: "8981040000000123401", ResourceID: "113000000001", OldIP: "192.168.1.2", NewIP: "192.168.1.3"},
+		}
+		if !reflect.DeepEqual(expected, entries) {
+			t.Fatalf("entries expected...%v, got ...%v\n", expected, entries)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("CIDR外の移動前のIPアドレスは使わない", func(t *testing.T) {
+		_, ipNet, _ := net.ParseCIDR("10.0.0.0/29")
+
+		entries, err := common.PlanMoveSim(testSourceSims, testDestSims, []*net.IPNet{ipNet})
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if entries[0].NewIP != "10.0.0.1" || entries[1].NewIP != "10.0.0.2" {
+			t.Fatalf("unexpected entries...%v", entries)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("移動先でIPアドレスが使用中でCIDRの指定がない場合エラーになる", func(t *testing.T) {
+		_, err := common.PlanMoveSim(testSourceSims, testDestSims, nil)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestSelectTargetSims(t *testing.T) {
+	t.Run("ICCIDの指定がない場合はすべてのSIMを対象とする", func(t *testing.T) {
+		targets, err := selectTargetSims(testSourceSims, nil)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if !reflect.DeepEqual(testSourceSims, targets) {
+			t.Fatalf("unexpected targets...%v", targets)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("移動元に登録されていないICCIDを指定するとエラーになる", func(t *testing.T) {
+		_, err := selectTargetSims(testSourceSims, []string{"8981040000000123499"})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	validOpts := Options{
+		AccessToken:         "token",
+		AccessTokenSecret:   "secret",
+		SourceZone:          "is1a",
+		SourceMgwResourceID: "000000000",
+		DestZone:            "tk1b",
+		DestMgwResourceID:   "000000001",
+		All:                 true,
+	}
+
+	t.Run("正しい引数", func(t *testing.T) {
+		_, err := validateArgs(validOpts)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		t.Log("OK")
+	})
+
+	t.Run("移動元と移動先が同じモバイルゲートウェイの場合エラーになる", func(t *testing.T) {
+		opts := validOpts
+		opts.DestZone = opts.SourceZone
+		opts.DestMgwResourceID = opts.SourceMgwResourceID
+		_, err := validateArgs(opts)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("--all とICCIDを同時に指定するとエラーになる", func(t *testing.T) {
+		opts := validOpts
+		opts.ICCID = []string{"8981040000000123400"}
+		_, err := validateArgs(opts)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
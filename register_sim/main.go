@@ -7,8 +7,9 @@ import (
 	"io"
 	"net"
 	"os"
-	"slices"
 	"strings"
+	"text/template"
+	"time"
 
 	flags "github.com/jessevdk/go-flags"
 	"github.com/sakura-internet/mobile-connect-commands/common"
@@ -16,58 +17,154 @@ import (
 
 // コマンドライン引数
 type Options struct {
-	CsvPath           string `long:"csv" description:"CSVファイルのパス"`
-	AccessToken       string `long:"token" description:"さくらのクラウドAPIアクセストークン"`
-	AccessTokenSecret string `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
-	Zone              string `long:"zone" description:"さくらのクラウドゾーン"`
-	CIDR              string `long:"cidr" description:"探索対象のCIDR"`
-	MgwResourceID     string `long:"mgw-resource-id" description:"モバイルゲートウェイのリソースID"`
-}
-
-// validateZone
-// 正しい Zone かチェックする
-func validateZone(zone string) error {
-	validZones := []string{"tk1a", "tk1b", "is1a", "is1b"}
-	if !slices.Contains(validZones, zone) {
-		return fmt.Errorf("不正なゾーンです。%s から指定してください", strings.Join(validZones, ", "))
-	}
-	return nil
+	CsvPath           string            `long:"csv" description:"CSVファイルのパス"`
+	AccessToken       string            `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string            `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Zone              string            `long:"zone" description:"さくらのクラウドゾーン"`
+	CIDR              []string          `long:"cidr" description:"探索対象のCIDR(複数指定可)"`
+	MgwResourceID     string            `long:"mgw-resource-id" description:"モバイルゲートウェイのリソースID"`
+	MgwName           string            `long:"mgw-name" description:"モバイルゲートウェイの名前(リソースIDの代わりに指定)"`
+	Activate          bool              `long:"activate" description:"登録後にSIMを有効化する"`
+	NameTemplate      string            `long:"name-template" description:"SIMの名前のテンプレート(例: {{.Site}}-{{.ICCID}})"`
+	TemplateVar       map[string]string `long:"template-var" key-value-delimiter:"=" description:"名前のテンプレートで使う変数(キー=値、複数指定可)"`
+	Report            string            `long:"report" description:"作成したSIMを記録するレポートのJSONファイルのパス(undo で取り消しに使う)"`
+	MaxMgwSims        int               `long:"max-mgw-sims" description:"モバイルゲートウェイに登録できるSIMの上限(0は上限と比較しない)"`
+	MaxAccountSims    int               `long:"max-account-sims" description:"アカウントに登録できるSIMの上限(0は上限と比較しない)"`
+	WarnOnly          bool              `long:"warn-only" description:"SIMの上限を超える場合も、警告を表示して登録を続ける"`
+	Verify            bool              `long:"verify" description:"登録後にモバイルゲートウェイのSIMの一覧を取得して登録結果を検証する"`
 }
 
 func validateCIDR(cidr string) (net.IP, *net.IPNet, error) {
-	// CIDR のパースだけ行う
+	// CIDR のパース
 	ip, ipNet, err := net.ParseCIDR(cidr)
 	if err != nil {
 		return ip, ipNet, fmt.Errorf("正しいフォーマットのCIDRを指定してください: %s", err.Error())
 	}
+
+	// SIM に割り当て可能な範囲かチェック
+	err = common.ValidateSimCIDR(ipNet)
+	if err != nil {
+		return ip, ipNet, err
+	}
 	return ip, ipNet, nil
 }
 
 // コマンドライン引数のバリデーションを行う
-func validateArgs(opts Options) (net.IP, *net.IPNet, error) {
+func validateArgs(opts Options) ([]*net.IPNet, error) {
 	if opts.CsvPath == "" {
-		return nil, nil, errors.New("コマンドライン引数にCSVファイルのパスを指定してください")
+		return nil, errors.New("コマンドライン引数にCSVファイルのパスを指定してください")
 	}
 
-	if opts.MgwResourceID == "" {
-		return nil, nil, errors.New("コマンドライン引数にモバイルゲートウェイのリソースIDを指定してください")
+	if (opts.MgwResourceID == "") == (opts.MgwName == "") {
+		return nil, errors.New("コマンドライン引数にモバイルゲートウェイのリソースIDか名前のいずれかを指定してください")
 	}
 
 	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
-		return nil, nil, errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+		return nil, errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
 	}
 
-	err := validateZone(opts.Zone)
+	err := common.ValidateZone(opts.Zone)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+
+	if len(opts.CIDR) == 0 {
+		return nil, errors.New("コマンドライン引数にCIDRを指定してください")
+	}
+
+	if opts.MaxMgwSims < 0 || opts.MaxAccountSims < 0 {
+		return nil, errors.New("SIMの上限には0以上を指定してください")
+	}
+
+	ipNets := make([]*net.IPNet, 0, len(opts.CIDR))
+	for _, cidr := range opts.CIDR {
+		_, ipNet, err := validateCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		ipNets = append(ipNets, ipNet)
 	}
 
-	ip, ipNet, err := validateCIDR(opts.CIDR)
+	err = common.CheckOverlappingCIDRs(ipNets)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return ip, ipNet, nil
+	return ipNets, nil
+}
+
+// parseNameTemplate
+// SIMの名前のテンプレートをパースする
+func parseNameTemplate(nameTemplate string, vars map[string]string) (*template.Template, error) {
+	if nameTemplate == "" {
+		if len(vars) > 0 {
+			return nil, errors.New("template-var は name-template と一緒に指定してください")
+		}
+		return nil, nil
+	}
+	for key := range vars {
+		if key == "ICCID" || key == "IMEI" {
+			return nil, fmt.Errorf("template-var に %s は指定できません", key)
+		}
+	}
+	tmpl, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("name-template が不正です...%s", err.Error())
+	}
+	return tmpl, nil
+}
+
+// applyNameTemplate
+// CSVで名前が指定されていないSIMにテンプレートから生成した名前を設定する
+func applyNameTemplate(sims []common.SimRegisterInfo, tmpl *template.Template, vars map[string]string) error {
+	if tmpl == nil {
+		return nil
+	}
+	for i := range sims {
+		if sims[i].Name != "" {
+			// CSVの名前を優先する
+			continue
+		}
+		data := make(map[string]string, len(vars)+2)
+		for key, value := range vars {
+			data[key] = value
+		}
+		data["ICCID"] = sims[i].ICCID
+		data["IMEI"] = sims[i].IMEI
+
+		var name strings.Builder
+		err := tmpl.Execute(&name, data)
+		if err != nil {
+			return fmt.Errorf("名前の生成に失敗しました(ICCID: %s)...%s", sims[i].ICCID, err.Error())
+		}
+		sims[i].Name = name.String()
+	}
+	return nil
+}
+
+// CSV の SIM の ICCID の一覧を返す
+func simICCIDs(simList []common.SimRegisterInfo) []string {
+	iccids := make([]string, 0, len(simList))
+	for _, sim := range simList {
+		iccids = append(iccids, sim.ICCID)
+	}
+	return iccids
+}
+
+// buildExpectations
+// CSV の SIM ごとに登録後に期待する状態を作成する
+// 登録済みでスキップした SIM は、IP アドレスが設定されていれば良いものとする
+func buildExpectations(simList []common.SimRegisterInfo, registered []common.RegisteredSim) []common.SimExpectation {
+	ipByICCID := make(map[string]string, len(registered))
+	for _, sim := range registered {
+		ipByICCID[sim.ICCID] = sim.IP
+	}
+
+	expectations := make([]common.SimExpectation, 0, len(simList))
+	for _, sim := range simList {
+		expectations = append(expectations, common.SimExpectation{ICCID: sim.ICCID, IP: ipByICCID[sim.ICCID]})
+	}
+	return expectations
 }
 
 func loadSimListCsv(csvPath string) ([]common.SimRegisterInfo, error) {
@@ -83,6 +180,8 @@ func loadSimListCsv(csvPath string) ([]common.SimRegisterInfo, error) {
 
 	// ICCIDをキーにパスコードを追加
 	reader := csv.NewReader(file)
+	// IMEI以降の列は省略できるので、列数は行ごとに異なっていても良い
+	reader.FieldsPerRecord = -1
 	for {
 		record, err := reader.Read()
 		if err != nil {
@@ -97,12 +196,41 @@ func loadSimListCsv(csvPath string) ([]common.SimRegisterInfo, error) {
 				}
 			}
 		}
-		if len(record) != 2 {
+		if len(record) < 2 || len(record) > 7 {
 			// フィールド数が一致しない
 			lineNo, _ := reader.FieldPos(0)
-			return nil, fmt.Errorf("%d行目:列数が正しくありません...2列必要ですが%d列読み込みました", lineNo, len(record))
+			return nil, fmt.Errorf("%d行目:列数が正しくありません...2列から7列必要ですが%d列読み込みました", lineNo, len(record))
+		}
+		info := common.SimRegisterInfo{ICCID: record[0], PassCode: record[1]}
+		// 3列目はIMEI(省略可)
+		if len(record) >= 3 && record[2] != "" {
+			err = common.ValidateIMEI(record[2])
+			if err != nil {
+				lineNo, _ := reader.FieldPos(0)
+				return nil, fmt.Errorf("%d行目:%s", lineNo, err.Error())
+			}
+			info.IMEI = record[2]
+		}
+		// 4列目は空白区切りの通信キャリア(省略可)
+		if len(record) >= 4 && strings.TrimSpace(record[3]) != "" {
+			info.Carriers, err = common.ParseCarriers(record[3])
+			if err != nil {
+				lineNo, _ := reader.FieldPos(0)
+				return nil, fmt.Errorf("%d行目:%s", lineNo, err.Error())
+			}
+		}
+		// 5列目は名前、6列目は説明(省略可)
+		if len(record) >= 5 {
+			info.Name = strings.TrimSpace(record[4])
+		}
+		if len(record) >= 6 {
+			info.Description = strings.TrimSpace(record[5])
 		}
-		sim = append(sim, common.SimRegisterInfo{ICCID: record[0], PassCode: record[1]})
+		// 7列目は空白区切りのタグ(省略可)
+		if len(record) == 7 {
+			info.Tags = strings.Fields(record[6])
+		}
+		sim = append(sim, info)
 	}
 
 	return sim, nil
@@ -119,7 +247,12 @@ func main() {
 		os.Exit(1)
 	}
 
-	ip, ipNet, err := validateArgs(opts)
+	ipNets, err := validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+	nameTemplate, err := parseNameTemplate(opts.NameTemplate, opts.TemplateVar)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
 		os.Exit(1)
@@ -134,27 +267,110 @@ func main() {
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
 		os.Exit(1)
 	}
+	err = applyNameTemplate(sim, nameTemplate, opts.TemplateVar)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
 	fmt.Println("[OK]")
 
+	// 名前が指定されていればMGWのリソースIDを取得
+	if opts.MgwName != "" {
+		fmt.Printf("モバイルゲートウェイ(%s)の検索中...", opts.MgwName)
+		opts.MgwResourceID, err = common.ResolveMgwID(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwName)
+		if err != nil {
+			// エラーメッセージを出力
+			fmt.Println("[NG]")
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("[OK] リソースID: %s\n", opts.MgwResourceID)
+	}
+
 	// MGWで使用中のIPアドレスのリストを取得
 	fmt.Printf("使用可能なIPアドレスの取得中...")
-	mgwIPAddrs, err := common.GetUsedIPAddressesInMGW(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwResourceID)
+	mgwSims, err := common.GetSimsInMGW(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwResourceID)
 	if err != nil {
 		// エラーメッセージを出力
 		fmt.Println("[NG]")
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
 		os.Exit(1)
 	}
+	mgwIPAddrs := common.UsedIPAddressesOfSims(mgwSims)
 	// 使用可能なIPアドレスのリストを取得
+	// 指定されたCIDRの順に埋めていく
 	availableIPAddrs := make([]string, 0, len(mgwIPAddrs))
-	for ipaddr := range common.GetAvailableIPAddresses(ip, ipNet, mgwIPAddrs) {
-		availableIPAddrs = append(availableIPAddrs, ipaddr)
+	for _, ipNet := range ipNets {
+		for ipaddr := range common.GetAvailableIPAddresses(ipNet.IP, ipNet, mgwIPAddrs) {
+			availableIPAddrs = append(availableIPAddrs, ipaddr)
+		}
 	}
 	fmt.Println("[OK]")
 
+	// CIDRがMGWのインタフェースのネットワークと重複していないか確認
+	fmt.Printf("モバイルゲートウェイのインタフェースの確認中...")
+	interfaceNets, err := common.GetMgwInterfaceNetworks(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwResourceID)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+	for _, warning := range common.GetInterfaceOverlapWarnings(ipNets, interfaceNets) {
+		// 重複していても登録は続ける
+		fmt.Fprintf(os.Stderr, "警告: %s\n", warning)
+	}
+
+	// 登録後のSIMの数が上限を超えないか確認
+	// 上限が指定されていない場合(0)も、登録済みのSIMの数と新規に登録するSIMの数を表示する
+	fmt.Printf("SIMの登録可能数の確認中...")
+	accountSims, err := common.GetSimsInAccount(opts.AccessToken, opts.AccessTokenSecret)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	usage := common.GetCapacityUsage(simICCIDs(sim), accountSims, mgwSims)
+	violations := common.CheckCapacity(usage, common.CapacityLimits{MaxMgwSims: opts.MaxMgwSims, MaxAccountSims: opts.MaxAccountSims})
+	if len(violations) > 0 && !opts.WarnOnly {
+		fmt.Println("[NG]")
+		for _, violation := range violations {
+			fmt.Fprintf(os.Stderr, "%s\n", violation)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("[OK] 新規 %d 枚(モバイルゲートウェイ: 登録済み %d 枚, アカウント: 登録済み %d 枚)\n", usage.NewSims, usage.MgwSims, usage.AccountSims)
+	for _, violation := range violations {
+		// --warn-only の場合は登録を続ける
+		fmt.Fprintf(os.Stderr, "警告: %s\n", violation)
+	}
+
 	// SIMを登録
+	registerOpts := common.RegisterOptions{Activate: opts.Activate}
+	if opts.Report != "" {
+		// 中断された場合も、それまでに作成したSIMが残るように処理が完了するたびに書き込む
+		report := common.RegisterReport{
+			CreatedAt:     time.Now().Format(time.RFC3339),
+			Zone:          opts.Zone,
+			MgwResourceID: opts.MgwResourceID,
+		}
+		// 書き込めることを登録の開始前に確認する
+		err = common.WriteRegisterReport(opts.Report, report)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(1)
+		}
+		registerOpts.OnProgress = func(registered []common.RegisteredSim) error {
+			report.Sims = registered
+			return common.WriteRegisterReport(opts.Report, report)
+		}
+	}
 	fmt.Println("SIM一括登録 開始")
-	err = common.RegisterSimFromList(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwResourceID, sim, availableIPAddrs)
+	registered, err := common.RegisterSimFromListWithOptions(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwResourceID, sim, availableIPAddrs, registerOpts)
 	if err != nil {
 		// 登録に失敗
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
@@ -163,5 +379,22 @@ func main() {
 
 	fmt.Println("SIM一括登録 完了")
 
+	if opts.Verify {
+		// 登録結果の検証
+		fmt.Println("登録結果の検証 開始")
+		mgwSims, err := common.GetSimsInMGW(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwResourceID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(1)
+		}
+		results := common.VerifySims(mgwSims, buildExpectations(sim, registered), ipNets)
+		mismatches := common.PrintVerifyResults(results)
+		fmt.Println("登録結果の検証 完了")
+		if mismatches > 0 {
+			fmt.Fprintf(os.Stderr, "登録結果が一致しないSIMが %d 枚あります\n", mismatches)
+			os.Exit(1)
+		}
+	}
+
 	os.Exit(0)
 }
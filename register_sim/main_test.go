@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/sakura-internet/mobile-connect-commands/common"
@@ -65,7 +66,7 @@ func init() {
 		os.Exit(1)
 	}
 
-	err = validateZone(testConfig.Zone)
+	err = common.ValidateZone(testConfig.Zone)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s", err.Error())
 		os.Exit(1)
@@ -90,6 +91,108 @@ func TestLoadSimListCsv(t *testing.T) {
 
 		t.Log("OK")
 	})
+
+	t.Run("3列目のIMEIを読み込む", func(t *testing.T) {
+		csvPath := "testdata/load_imei_test.csv"
+
+		simList, err := loadSimListCsv(csvPath)
+		if err != nil {
+			t.Fatalf("CSVファイルが読み込めません。%s", err.Error())
+		}
+
+		if len(simList) != 3 || simList[0].IMEI != "350000000000000" || simList[1].IMEI != "" || simList[2].IMEI != "" {
+			t.Fatalf("unexpected sim list...%v", simList)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("4列目の通信キャリアを読み込む", func(t *testing.T) {
+		csvPath := "testdata/load_carrier_test.csv"
+
+		simList, err := loadSimListCsv(csvPath)
+		if err != nil {
+			t.Fatalf("CSVファイルが読み込めません。%s", err.Error())
+		}
+
+		if len(simList) != 3 ||
+			!reflect.DeepEqual(simList[0].Carriers, []string{"docomo", "softbank"}) ||
+			!reflect.DeepEqual(simList[1].Carriers, []string{"kddi"}) || simList[1].IMEI != "" ||
+			simList[2].Carriers != nil {
+			t.Fatalf("unexpected sim list...%v", simList)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("5列目から7列目の名前、説明、タグを読み込む", func(t *testing.T) {
+		csvPath := "testdata/load_name_test.csv"
+
+		simList, err := loadSimListCsv(csvPath)
+		if err != nil {
+			t.Fatalf("CSVファイルが読み込めません。%s", err.Error())
+		}
+
+		if len(simList) != 3 ||
+			simList[0].Name != "tokyo-001" || simList[0].Description != "東京拠点のルータ" ||
+			!reflect.DeepEqual(simList[0].Tags, []string{"tokyo", "router"}) ||
+			simList[1].Name != "" || len(simList[1].Tags) != 0 || simList[1].IMEI != "350000000000000" ||
+			simList[2].Name != "" {
+			t.Fatalf("unexpected sim list...%v", simList)
+		}
+		t.Log("OK")
+	})
+}
+
+func TestNameTemplate(t *testing.T) {
+	t.Run("CSVで名前が無いSIMにテンプレートから名前を設定する", func(t *testing.T) {
+		vars := map[string]string{"Site": "tokyo"}
+		tmpl, err := parseNameTemplate("{{.Site}}-{{.ICCID}}", vars)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		sims := []common.SimRegisterInfo{
+			{ICCID: "8981040000000123400", Name: "csv-name"},
+			{ICCID: "8981040000000123401"},
+		}
+		err = applyNameTemplate(sims, tmpl, vars)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if sims[0].Name != "csv-name" || sims[1].Name != "tokyo-8981040000000123401" {
+			t.Fatalf("unexpected sim list...%v", sims)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("テンプレートの変数が指定されていないとエラーになる", func(t *testing.T) {
+		tmpl, err := parseNameTemplate("{{.Site}}-{{.ICCID}}", nil)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		err = applyNameTemplate([]common.SimRegisterInfo{{ICCID: "8981040000000123400"}}, tmpl, nil)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("不正なテンプレートはエラーになる", func(t *testing.T) {
+		_, err := parseNameTemplate("{{.Site", nil)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("テンプレート無しで変数を指定するとエラーになる", func(t *testing.T) {
+		_, err := parseNameTemplate("", map[string]string{"Site": "tokyo"})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
 }
 
 func TestRegisterSimFromList(t *testing.T) {
@@ -170,7 +273,7 @@ func TestValidateCIDR(t *testing.T) {
 func TestValidateZone(t *testing.T) {
 	t.Run("不正なゾーンを入力したら、エラーが返る", func(t *testing.T) {
 		zone := "tk3a"
-		err := validateZone(zone)
+		err := common.ValidateZone(zone)
 		if err != nil {
 			t.Log("OK")
 		} else {
@@ -180,8 +283,8 @@ func TestValidateZone(t *testing.T) {
 }
 func TestValidateArgs(t *testing.T) {
 	t.Run("アクセストークン、アクセストークンシークレットが無いとエラーになる", func(t *testing.T) {
-		options := Options{CsvPath: "testdata.csv", AccessToken: "", AccessTokenSecret: "", Zone: "is1a", CIDR: "192.168.1.0/29", MgwResourceID: "aaaaaaa"}
-		_, _, err := validateArgs(options)
+		options := Options{CsvPath: "testdata.csv", AccessToken: "", AccessTokenSecret: "", Zone: "is1a", CIDR: []string{"192.168.1.0/29"}, MgwResourceID: "aaaaaaa"}
+		_, err := validateArgs(options)
 		if err != nil {
 			t.Log("OK")
 		} else {
@@ -190,12 +293,79 @@ func TestValidateArgs(t *testing.T) {
 	})
 
 	t.Run("CSVファイルのパスが無いとエラーになる", func(t *testing.T) {
-		options := Options{CsvPath: "", AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", CIDR: "192.168.1.0/29", MgwResourceID: "aaaaaaa"}
-		_, _, err := validateArgs(options)
+		options := Options{CsvPath: "", AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", CIDR: []string{"192.168.1.0/29"}, MgwResourceID: "aaaaaaa"}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+	t.Run("範囲が重複するCIDRを指定するとエラーになる", func(t *testing.T) {
+		options := Options{CsvPath: "testdata.csv", AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", CIDR: []string{"192.168.1.0/29", "192.168.1.0/28"}, MgwResourceID: "aaaaaaa"}
+		_, err := validateArgs(options)
 		if err != nil {
 			t.Log("OK")
 		} else {
 			t.Fatalf("error is expected")
 		}
 	})
+	t.Run("モバイルゲートウェイのリソースIDも名前も無いとエラーになる", func(t *testing.T) {
+		options := Options{CsvPath: "testdata.csv", AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", CIDR: []string{"192.168.1.0/29"}}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestBuildExpectations(t *testing.T) {
+	t.Run("登録したSIMは割り当てたIPアドレス、スキップしたSIMはIPアドレス無しで期待する", func(t *testing.T) {
+		simList := []common.SimRegisterInfo{{ICCID: "8981040000000123400"}, {ICCID: "8981040000000123401"}}
+		registered := []common.RegisteredSim{{ICCID: "8981040000000123401", ResourceID: "290000000001", IP: "192.168.1.1"}}
+
+		expected := []common.SimExpectation{{ICCID: "8981040000000123400"}, {ICCID: "8981040000000123401", IP: "192.168.1.1"}}
+		expectations := buildExpectations(simList, registered)
+		if !reflect.DeepEqual(expected, expectations) {
+			t.Fatalf("expectations expected...%v, got ...%v\n", expected, expectations)
+		}
+		t.Log("OK")
+	})
+}
+
+func TestCapacity(t *testing.T) {
+	accountSim := common.AccountSim{ID: "290000000000"}
+	accountSim.Status.ICCID = "8981040000000123400"
+	accountSims := []common.AccountSim{accountSim}
+	mgwSims := []common.MgwSim{{ICCID: "8981040000000123400", IP: "192.168.1.1"}}
+	iccids := []string{"8981040000000123400", "8981040000000123401", "8981040000000123402"}
+
+	t.Run("アカウントに登録済みのSIMは新規に数えない", func(t *testing.T) {
+		usage := common.GetCapacityUsage(iccids, accountSims, mgwSims)
+		expected := common.CapacityUsage{MgwSims: 1, AccountSims: 1, NewSims: 2}
+		if usage != expected {
+			t.Fatalf("usage expected...%v, got ...%v\n", expected, usage)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("上限を超える場合は内容を返す", func(t *testing.T) {
+		usage := common.GetCapacityUsage(iccids, accountSims, mgwSims)
+		violations := common.CheckCapacity(usage, common.CapacityLimits{MaxMgwSims: 2, MaxAccountSims: 3})
+		if len(violations) != 1 {
+			t.Fatalf("unexpected violations...%v", violations)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("上限が0の場合は確認しない", func(t *testing.T) {
+		usage := common.GetCapacityUsage(iccids, accountSims, mgwSims)
+		violations := common.CheckCapacity(usage, common.CapacityLimits{})
+		if len(violations) != 0 {
+			t.Fatalf("unexpected violations...%v", violations)
+		}
+		t.Log("OK")
+	})
 }
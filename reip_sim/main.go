@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strings"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	AccessToken       string   `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string   `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Zone              string   `long:"zone" description:"さくらのクラウドゾーン"`
+	MgwResourceID     string   `long:"mgw-resource-id" description:"モバイルゲートウェイのリソースID"`
+	CIDR              []string `long:"cidr" description:"変更後のIPアドレスを割り当てるCIDR(複数指定可)"`
+	MappingCsvPath    string   `long:"mapping" description:"ICCIDと変更後のIPアドレスを記載したCSVファイルのパス"`
+	CsvPath           string   `long:"csv" description:"変更対象のSIMを記載したCSVファイルのパス(1列目のICCIDのみ参照)"`
+	ICCID             []string `long:"iccid" description:"変更対象のSIMのICCID(複数指定可)"`
+	SourceCIDR        []string `long:"source-cidr" description:"変更対象のSIMを変更前のIPアドレスの範囲で絞り込む(複数指定可)"`
+	DryRun            bool     `long:"dry-run" description:"APIを呼び出さずに変更内容のみ表示する"`
+}
+
+// ICCID と変更後の IP アドレスの組
+type Mapping struct {
+	ICCID string
+	IP    string
+}
+
+// CIDR のリストをパースする
+// checkSimRule が true の場合は SIM に割り当て可能な範囲かもチェックする
+func parseCIDRs(cidrs []string, checkSimRule bool) ([]*net.IPNet, error) {
+	ipNets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("正しいフォーマットのCIDRを指定してください: %s", err.Error())
+		}
+		if checkSimRule {
+			err = common.ValidateSimCIDR(ipNet)
+			if err != nil {
+				return nil, err
+			}
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, nil
+}
+
+// コマンドライン引数のバリデーションを行い、変更後の CIDR と変更対象を絞り込む CIDR を返す
+func validateArgs(opts Options) ([]*net.IPNet, []*net.IPNet, error) {
+	if opts.MgwResourceID == "" {
+		return nil, nil, errors.New("コマンドライン引数にモバイルゲートウェイのリソースIDを指定してください")
+	}
+
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return nil, nil, errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	err := common.ValidateZone(opts.Zone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if (len(opts.CIDR) > 0) == (opts.MappingCsvPath != "") {
+		return nil, nil, errors.New("コマンドライン引数にCIDRかマッピングのCSVファイルのいずれかを指定してください")
+	}
+
+	if opts.MappingCsvPath != "" {
+		if opts.CsvPath != "" || len(opts.ICCID) > 0 || len(opts.SourceCIDR) > 0 {
+			return nil, nil, errors.New("マッピングのCSVファイルを指定した場合は、変更対象のSIMを絞り込むことはできません")
+		}
+		return nil, nil, nil
+	}
+
+	ipNets, err := parseCIDRs(opts.CIDR, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = common.CheckOverlappingCIDRs(ipNets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sourceIPNets, err := parseCIDRs(opts.SourceCIDR, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ipNets, sourceIPNets, nil
+}
+
+// ICCID, 変更後の IP アドレス の CSV ファイルを読み込む
+func loadMappingCsv(csvPath string) ([]Mapping, error) {
+	mappings := make([]Mapping, 0, 100)
+
+	// CSVファイルを開く
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("CSVファイルのオープンに失敗しました...%s", err.Error())
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// ファイルの末尾に到達
+				break
+			}
+			return nil, fmt.Errorf("読み込みに失敗しました...%s", err.Error())
+		}
+		if len(record) != 2 {
+			// フィールド数が一致しない
+			lineNo, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%d行目:列数が正しくありません...2列必要ですが%d列読み込みました", lineNo, len(record))
+		}
+		mappings = append(mappings, Mapping{ICCID: strings.TrimSpace(record[0]), IP: strings.TrimSpace(record[1])})
+	}
+
+	return mappings, nil
+}
+
+// マッピングからモバイルゲートウェイ配下の SIM の変更内容を作成する
+// 変更前と変更後の IP アドレスが同じ SIM は除く
+func buildMappingEntries(mappings []Mapping, sims []common.MgwSim) ([]common.ReIPEntry, error) {
+	simByICCID := make(map[string]common.MgwSim)
+	for _, sim := range sims {
+		simByICCID[sim.ICCID] = sim
+	}
+
+	entries := make([]common.ReIPEntry, 0, len(mappings))
+	for _, mapping := range mappings {
+		sim, exists := simByICCID[mapping.ICCID]
+		if !exists {
+			return nil, fmt.Errorf("ICCID %s のSIMはモバイルゲートウェイに登録されていません", mapping.ICCID)
+		}
+		if sim.IP == mapping.IP {
+			continue
+		}
+		entries = append(entries, common.ReIPEntry{ICCID: sim.ICCID, ResourceID: sim.ResourceID, OldIP: sim.IP, NewIP: mapping.IP})
+	}
+
+	err := common.ValidateReIPEntries(entries, sims)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// モバイルゲートウェイ配下の SIM から変更対象を選ぶ
+func selectTargetSims(sims []common.MgwSim, iccids []string, sourceIPNets []*net.IPNet) []common.MgwSim {
+	targets := sims
+	if len(sourceIPNets) > 0 {
+		targets = common.FilterSimsByCIDRs(sourceIPNets, targets)
+	}
+	if len(iccids) > 0 {
+		filtered := make([]common.MgwSim, 0, len(targets))
+		for _, sim := range targets {
+			if slices.Contains(iccids, sim.ICCID) {
+				filtered = append(filtered, sim)
+			}
+		}
+		targets = filtered
+	}
+	return targets
+}
+
+// 変更内容を作成する
+func buildEntries(opts Options, ipNets []*net.IPNet, sourceIPNets []*net.IPNet) ([]common.ReIPEntry, error) {
+	sims, err := common.GetSimsInMGW(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwResourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MappingCsvPath != "" {
+		mappings, err := loadMappingCsv(opts.MappingCsvPath)
+		if err != nil {
+			return nil, err
+		}
+		return buildMappingEntries(mappings, sims)
+	}
+
+	iccids, err := common.LoadICCIDList(opts.CsvPath, opts.ICCID)
+	if err != nil {
+		return nil, err
+	}
+	targets := selectTargetSims(sims, iccids, sourceIPNets)
+	return common.PlanReIP(targets, ipNets, common.UsedIPAddressesOfSims(sims))
+}
+
+func main() {
+	// コマンドライン引数の確認
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	ipNets, sourceIPNets, err := validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// 変更内容の作成
+	fmt.Printf("IPアドレスの変更内容の作成中...")
+	entries, err := buildEntries(opts, ipNets, sourceIPNets)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+
+	if opts.DryRun {
+		fmt.Println("IPアドレス一括変更 開始(ドライラン)")
+	} else {
+		fmt.Println("IPアドレス一括変更 開始")
+	}
+	err = common.ReIPSimFromList(opts.AccessToken, opts.AccessTokenSecret, entries, opts.DryRun)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("IPアドレス一括変更 完了")
+
+	os.Exit(0)
+}
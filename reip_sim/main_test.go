@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// テストに使用するモバイルゲートウェイ配下の SIM 一覧
+var testSims = []common.MgwSim{
+	{ICCID: "8981040000000123400", ResourceID: "113000000000", IP: "192.168.1.1"},
+	{ICCID: "8981040000000123401", ResourceID: "113000000001", IP: "192.168.1.2"},
+	{ICCID: "8981040000000123402", ResourceID: "113000000002", IP: "10.0.0.1"},
+}
+
+func TestPlanReIP(t *testing.T) {
+	t.Run("CIDR外のSIMにCIDR内の未使用のIPアドレスを順に割り当てる", func(t *testing.T) {
+		_, ipNet, _ := net.ParseCIDR("10.0.0.0/29")
+
+		entries, err := common.PlanReIP(testSims, []*net.IPNet{ipNet}, common.UsedIPAddressesOfSims(testSims))
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+
+		expected := []common.ReIPEntry{
+			{ICCID: "8981040000000123400", ResourceID: "113000000000", OldIP: "192.168.1.1", NewIP: "10.0.0.2"},
+			{ICCID: "8981040000000123401", ResourceID: "113000000001", OldIP: "192.168.1.2", NewIP: "10.0.0.3"},
+		}
+		if !reflect.DeepEqual(expected, entries) {
+			t.Fatalf("entries expected...%v, got ...%v\n", expected, entries)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("割り当て可能なIPアドレスが不足している場合エラーになる", func(t *testing.T) {
+		_, ipNet, _ := net.ParseCIDR("10.0.0.0/30")
+
+		_, err := common.PlanReIP(testSims, []*net.IPNet{ipNet}, common.UsedIPAddressesOfSims(testSims))
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestBuildMappingEntries(t *testing.T) {
+	t.Run("マッピングのCSVファイルから変更内容を作成する", func(t *testing.T) {
+		mappings, err := loadMappingCsv("testdata/mapping_test.csv")
+		if err != nil {
+			t.Fatalf("CSVファイルが読み込めません。%s", err.Error())
+		}
+
+		entries, err := buildMappingEntries(mappings, testSims)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if len(entries) != 1 {
+			t.Fatalf("unexpected entries...%v", entries)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("他のSIMが使用中のIPアドレスに変更しようとするとエラーになる", func(t *testing.T) {
+		mappings := []Mapping{
+			{ICCID: "8981040000000123400", IP: "192.168.1.2"},
+		}
+		_, err := buildMappingEntries(mappings, testSims)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("変更後のIPアドレスが重複しているとエラーになる", func(t *testing.T) {
+		mappings := []Mapping{
+			{ICCID: "8981040000000123400", IP: "10.0.0.5"},
+			{ICCID: "8981040000000123401", IP: "10.0.0.5"},
+		}
+		_, err := buildMappingEntries(mappings, testSims)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("モバイルゲートウェイに登録されていないSIMはエラーになる", func(t *testing.T) {
+		mappings := []Mapping{
+			{ICCID: "8981040000000123499", IP: "10.0.0.5"},
+		}
+		_, err := buildMappingEntries(mappings, testSims)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestSelectTargetSims(t *testing.T) {
+	t.Run("ICCIDと変更前のCIDRで絞り込む", func(t *testing.T) {
+		_, sourceIPNet, _ := net.ParseCIDR("192.168.1.0/24")
+
+		targets := selectTargetSims(testSims, []string{"8981040000000123401", "8981040000000123402"}, []*net.IPNet{sourceIPNet})
+		if len(targets) != 1 || targets[0].ICCID != "8981040000000123401" {
+			t.Fatalf("unexpected targets...%v", targets)
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("CIDRとマッピングの両方を指定するとエラーになる", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", MgwResourceID: "aaaaaaa", CIDR: []string{"10.0.0.0/24"}, MappingCsvPath: "testdata/mapping_test.csv"}
+		_, _, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("SIMに割り当てできないCIDRはエラーになる", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", MgwResourceID: "aaaaaaa", CIDR: []string{"203.0.113.0/24"}}
+		_, _, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("マッピングと絞り込みを同時に指定するとエラーになる", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", MgwResourceID: "aaaaaaa", MappingCsvPath: "testdata/mapping_test.csv", SourceCIDR: []string{"192.168.1.0/24"}}
+		_, _, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
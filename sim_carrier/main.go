@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	CsvPath           string   `long:"csv" description:"CSVファイルのパス(1列目のICCIDのみ参照)"`
+	ICCID             []string `long:"iccid" description:"対象のSIMのICCID(複数指定可)"`
+	AccessToken       string   `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string   `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Zone              string   `long:"zone" description:"さくらのクラウドゾーン(モバイルゲートウェイから対象を選ぶ場合)"`
+	MgwResourceID     string   `long:"mgw-resource-id" description:"モバイルゲートウェイのリソースID(モバイルゲートウェイから対象を選ぶ場合)"`
+	MgwName           string   `long:"mgw-name" description:"モバイルゲートウェイの名前(リソースIDの代わりに指定)"`
+	CIDR              []string `long:"cidr" description:"モバイルゲートウェイから対象を選ぶ場合に、IPアドレスの範囲で絞り込む(複数指定可)"`
+	Carrier           []string `long:"carrier" description:"利用を許可する通信キャリア(docomo, kddi, softbank、複数指定可)。省略時は現在の設定を出力する"`
+	Output            string   `long:"output" default:"table" description:"現在の設定の出力形式(table, json, csv)"`
+}
+
+// SIM の通信キャリアの設定
+type SimCarriers struct {
+	ICCID      string   `json:"iccid"`
+	ResourceID string   `json:"resource_id"`
+	Carriers   []string `json:"carriers"`
+}
+
+// コマンドライン引数のバリデーションを行い、絞り込みに使う CIDR と設定する通信キャリアを返す
+func validateArgs(opts Options) ([]*net.IPNet, []string, error) {
+	fromList := opts.CsvPath != "" || len(opts.ICCID) > 0
+	fromMgw := opts.MgwResourceID != "" || opts.MgwName != ""
+	if fromList == fromMgw {
+		return nil, nil, errors.New("コマンドライン引数にCSVファイルのパスかICCID、またはモバイルゲートウェイのいずれかを指定してください")
+	}
+	if opts.MgwResourceID != "" && opts.MgwName != "" {
+		return nil, nil, errors.New("モバイルゲートウェイのリソースIDと名前は同時に指定できません")
+	}
+
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return nil, nil, errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	err := common.ValidateOutput(opts.Output, []string{"table", "json", "csv"})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var carriers []string
+	if len(opts.Carrier) > 0 {
+		carriers, err = common.ValidateCarriers(opts.Carrier)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if !fromMgw {
+		if len(opts.CIDR) > 0 {
+			return nil, nil, errors.New("CIDRによる絞り込みはモバイルゲートウェイを指定した場合のみ利用できます")
+		}
+		return nil, carriers, nil
+	}
+
+	err = common.ValidateZone(opts.Zone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ipNets := make([]*net.IPNet, 0, len(opts.CIDR))
+	for _, cidr := range opts.CIDR {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("正しいフォーマットのCIDRを指定してください: %s", err.Error())
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, carriers, nil
+}
+
+// 対象の SIM を取得する
+func loadTargetSims(opts Options, ipNets []*net.IPNet) ([]common.MgwSim, error) {
+	if opts.MgwResourceID != "" || opts.MgwName != "" {
+		mgwID := opts.MgwResourceID
+		if opts.MgwName != "" {
+			var err error
+			mgwID, err = common.ResolveMgwID(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwName)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// モバイルゲートウェイ配下の SIM から選ぶ
+		sims, err := common.GetSimsInMGW(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, mgwID)
+		if err != nil {
+			return nil, err
+		}
+		if len(ipNets) > 0 {
+			sims = common.FilterSimsByCIDRs(ipNets, sims)
+		}
+		return sims, nil
+	}
+
+	// CSVファイル、コマンドライン引数の ICCID からアカウント内の SIM を探す
+	iccids, err := common.LoadICCIDList(opts.CsvPath, opts.ICCID)
+	if err != nil {
+		return nil, err
+	}
+	return common.FindSimsByICCID(opts.AccessToken, opts.AccessTokenSecret, iccids)
+}
+
+// 通信キャリアの設定を指定された形式で出力する
+func writeSimCarriers(w io.Writer, output string, list []SimCarriers) error {
+	switch output {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(list)
+	case "csv":
+		writer := csv.NewWriter(w)
+		err := writer.Write([]string{"iccid", "resource_id", "carriers"})
+		if err != nil {
+			return err
+		}
+		for _, s := range list {
+			err = writer.Write([]string{s.ICCID, s.ResourceID, strings.Join(s.Carriers, " ")})
+			if err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, err := fmt.Fprintln(writer, "ICCID\tRESOURCE_ID\tCARRIERS")
+		if err != nil {
+			return err
+		}
+		for _, s := range list {
+			_, err = fmt.Fprintf(writer, "%s\t%s\t%s\n", s.ICCID, s.ResourceID, strings.Join(s.Carriers, ","))
+			if err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+	}
+}
+
+// 現在の通信キャリアの設定を取得する
+func getSimCarriers(opts Options, sims []common.MgwSim) ([]SimCarriers, error) {
+	list := make([]SimCarriers, 0, len(sims))
+	for _, sim := range sims {
+		carriers, err := common.GetSimCarriers(opts.AccessToken, opts.AccessTokenSecret, sim.ResourceID)
+		if err != nil {
+			return nil, fmt.Errorf("ICCID: %s...%s", sim.ICCID, err.Error())
+		}
+		list = append(list, SimCarriers{ICCID: sim.ICCID, ResourceID: sim.ResourceID, Carriers: carriers})
+	}
+	return list, nil
+}
+
+func main() {
+	// コマンドライン引数の確認
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	ipNets, carriers, err := validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	if len(carriers) == 0 {
+		// 現在の設定を出力する
+		// 結果をパイプで渡せるように、標準エラー出力に出す
+		fmt.Fprintln(os.Stderr, "情報を取得しています...")
+		sims, err := loadTargetSims(opts, ipNets)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		list, err := getSimCarriers(opts, sims)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		err = writeSimCarriers(os.Stdout, opts.Output, list)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "結果の出力に失敗しました...%s\n", err.Error())
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// 対象のSIMの取得
+	fmt.Printf("対象のSIMの取得中...")
+	sims, err := loadTargetSims(opts, ipNets)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+
+	fmt.Println("通信キャリア一括設定 開始")
+	err = common.SetSimCarriersFromList(opts.AccessToken, opts.AccessTokenSecret, sims, carriers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("通信キャリア一括設定 完了")
+
+	os.Exit(0)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+func TestValidateCarriers(t *testing.T) {
+	t.Run("大文字小文字を区別せず、決まった順に並べて重複を除く", func(t *testing.T) {
+		carriers, err := common.ValidateCarriers([]string{"SoftBank", "docomo", "softbank"})
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := []string{"docomo", "softbank"}
+		if !reflect.DeepEqual(expected, carriers) {
+			t.Fatalf("carriers expected...%v, got ...%v\n", expected, carriers)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("不正な通信キャリアを指定するとエラーになる", func(t *testing.T) {
+		_, err := common.ValidateCarriers([]string{"docomo", "au"})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("空白区切りの指定をパースする", func(t *testing.T) {
+		carriers, err := common.ParseCarriers(" kddi  docomo ")
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if !reflect.DeepEqual([]string{"docomo", "kddi"}, carriers) {
+			t.Fatalf("unexpected carriers...%v", carriers)
+		}
+		t.Log("OK")
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("モバイルゲートウェイを指定しない場合はCIDRで絞り込めない", func(t *testing.T) {
+		options := Options{ICCID: []string{"8981040000000123400"}, AccessToken: "Token", AccessTokenSecret: "Secret", CIDR: []string{"192.168.1.0/24"}, Output: "table"}
+		_, _, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("通信キャリアを省略すると現在の設定を出力する", func(t *testing.T) {
+		options := Options{ICCID: []string{"8981040000000123400"}, AccessToken: "Token", AccessTokenSecret: "Secret", Output: "table"}
+		_, carriers, err := validateArgs(options)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if len(carriers) != 0 {
+			t.Fatalf("unexpected carriers...%v", carriers)
+		}
+		t.Log("OK")
+	})
+}
+
+func TestWriteSimCarriers(t *testing.T) {
+	t.Run("CSV形式では通信キャリアを空白区切りで出力する", func(t *testing.T) {
+		list := []SimCarriers{
+			{ICCID: "8981040000000123400", ResourceID: "113000000000", Carriers: []string{"docomo", "softbank"}},
+		}
+		var buf bytes.Buffer
+		err := writeSimCarriers(&buf, "csv", list)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := "iccid,resource_id,carriers\n8981040000000123400,113000000000,docomo softbank\n"
+		if buf.String() != expected {
+			t.Fatalf("output expected...%s, got ...%s\n", expected, buf.String())
+		} else {
+			t.Log("OK")
+		}
+	})
+}
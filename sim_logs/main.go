@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	CsvPath           string   `long:"csv" description:"CSVファイルのパス(1列目のICCIDのみ参照)"`
+	ICCID             []string `long:"iccid" description:"対象のSIMのICCID(複数指定可)"`
+	AccessToken       string   `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string   `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Follow            bool     `long:"follow" description:"中断されるまで新しいログを取得して出力し続ける"`
+	Interval          int      `long:"interval" default:"10" description:"--follow 指定時にログを取得する間隔(秒)"`
+}
+
+// 出力するログ
+type LogEntry struct {
+	ICCID string `json:"iccid"`
+	common.SimLog
+}
+
+// SIM ごとの出力済みのログの一覧
+// 取得したログに含まれるものだけを残し、--follow で出力し続けても増え続けないようにする
+type seenLogs map[string]map[string]struct{}
+
+// コマンドライン引数のバリデーションを行う
+func validateArgs(opts Options) error {
+	if opts.CsvPath == "" && len(opts.ICCID) == 0 {
+		return errors.New("コマンドライン引数にCSVファイルのパスかICCIDを指定してください")
+	}
+
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	if opts.Interval <= 0 {
+		return errors.New("ログを取得する間隔は1秒以上を指定してください")
+	}
+
+	return nil
+}
+
+// まだ出力していないログのみを返し、出力済みとして記録する
+// 取得したログに含まれなくなった古いログは、再び取得されることはないので記録から削除する
+func newLogEntries(sim common.MgwSim, logs []common.SimLog, seen seenLogs) []LogEntry {
+	entries := make([]LogEntry, 0, len(logs))
+	current := make(map[string]struct{}, len(logs))
+	for _, log := range logs {
+		key := fmt.Sprintf("%s|%s|%s", log.Date, log.SessionStatus, log.IMEI)
+		current[key] = struct{}{}
+		if _, exists := seen[sim.ICCID][key]; exists {
+			continue
+		}
+		entries = append(entries, LogEntry{ICCID: sim.ICCID, SimLog: log})
+	}
+	seen[sim.ICCID] = current
+	return entries
+}
+
+// ログを JSON Lines 形式で出力する
+func writeLogEntries(w io.Writer, entries []LogEntry) error {
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		err := encoder.Encode(entry)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 全ての SIM のログを取得し、まだ出力していないものを日時順に出力する
+func fetchAndWriteLogs(w io.Writer, opts Options, sims []common.MgwSim, seen seenLogs) error {
+	entries := make([]LogEntry, 0)
+	for _, sim := range sims {
+		logs, err := common.GetSimLogs(opts.AccessToken, opts.AccessTokenSecret, sim.ResourceID)
+		if err != nil {
+			return fmt.Errorf("ICCID: %s...%s", sim.ICCID, err.Error())
+		}
+		entries = append(entries, newLogEntries(sim, logs, seen)...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Date < entries[j].Date })
+
+	err := writeLogEntries(w, entries)
+	if err != nil {
+		return fmt.Errorf("結果の出力に失敗しました...%s", err.Error())
+	}
+	return nil
+}
+
+func main() {
+	// コマンドラインオプションのパース
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// コマンドライン引数を バリデーションする
+	err = validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// 結果をパイプで渡せるように、標準エラー出力に出す
+	fmt.Fprintln(os.Stderr, "情報を取得しています...")
+
+	// CSVファイル、コマンドライン引数の ICCID からアカウント内の SIM を探す
+	iccids, err := common.LoadICCIDList(opts.CsvPath, opts.ICCID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	sims, err := common.FindSimsByICCID(opts.AccessToken, opts.AccessTokenSecret, iccids)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	seen := make(seenLogs)
+	err = fetchAndWriteLogs(os.Stdout, opts, sims, seen)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	if !opts.Follow {
+		os.Exit(0)
+	}
+
+	// 中断されるまで新しいログを出力し続ける
+	fmt.Fprintf(os.Stderr, "%d秒ごとに新しいログを取得します。終了するには Ctrl+C を押してください\n", opts.Interval)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(time.Duration(opts.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err = fetchAndWriteLogs(os.Stdout, opts, sims, seen)
+			if err != nil {
+				// 一時的なエラーの可能性があるので、取得は続ける
+				fmt.Fprintf(os.Stderr, "警告: %s\n", err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// テストに使用する SIM
+var testSim = common.MgwSim{ICCID: "8981040000000123400", ResourceID: "113000000000"}
+
+// テストに使用するセッションログ
+var testLogs = []common.SimLog{
+	{Date: "2024-04-01T10:00:00+09:00", SessionStatus: "Created", ResourceID: "113000000000", IMEI: "352555093320000", IMSI: "440100000000000"},
+	{Date: "2024-04-01T11:00:00+09:00", SessionStatus: "Deleted", ResourceID: "113000000000", IMEI: "352555093320000", IMSI: "440100000000000"},
+}
+
+func TestNewLogEntries(t *testing.T) {
+	t.Run("出力済みのログは返さない", func(t *testing.T) {
+		seen := make(seenLogs)
+		first := newLogEntries(testSim, testLogs[:1], seen)
+		if len(first) != 1 {
+			t.Fatalf("unexpected entries...%v", first)
+		}
+
+		second := newLogEntries(testSim, testLogs, seen)
+		if len(second) != 1 || second[0].SessionStatus != "Deleted" {
+			t.Fatalf("unexpected entries...%v", second)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("取得したログに含まれなくなったログは記録から削除する", func(t *testing.T) {
+		seen := make(seenLogs)
+		newLogEntries(testSim, testLogs, seen)
+
+		// 古いログが取得範囲から外れた場合
+		entries := newLogEntries(testSim, testLogs[1:], seen)
+		if len(entries) != 0 {
+			t.Fatalf("unexpected entries...%v", entries)
+		}
+		if len(seen[testSim.ICCID]) != 1 {
+			t.Fatalf("seen logs expected...%d, got ...%d\n", 1, len(seen[testSim.ICCID]))
+		}
+		t.Log("OK")
+	})
+}
+
+func TestWriteLogEntries(t *testing.T) {
+	t.Run("ICCIDを含めてJSON Lines形式で出力する", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeLogEntries(&buf, newLogEntries(testSim, testLogs, make(seenLogs)))
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := `{"iccid":"8981040000000123400","date":"2024-04-01T10:00:00+09:00","session_status":"Created","resource_id":"113000000000","imei":"352555093320000","imsi":"440100000000000"}` + "\n" +
+			`{"iccid":"8981040000000123400","date":"2024-04-01T11:00:00+09:00","session_status":"Deleted","resource_id":"113000000000","imei":"352555093320000","imsi":"440100000000000"}` + "\n"
+		if buf.String() != expected {
+			t.Fatalf("output expected...%s, got ...%s\n", expected, buf.String())
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("ICCIDが無いとエラーになる", func(t *testing.T) {
+		err := validateArgs(Options{AccessToken: "Token", AccessTokenSecret: "Secret", Interval: 10})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("取得間隔が0秒だとエラーになる", func(t *testing.T) {
+		err := validateArgs(Options{ICCID: []string{"8981040000000123400"}, AccessToken: "Token", AccessTokenSecret: "Secret", Follow: true, Interval: 0})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
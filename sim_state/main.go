@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+	"gopkg.in/yaml.v3"
+)
+
+// コマンドライン引数
+type Options struct {
+	AccessToken       string `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	StatePath         string `long:"file" description:"あるべき状態を記載したファイルのパス(YAMLまたはJSON)"`
+	Args              struct {
+		Action string `positional-arg-name:"plan|apply"`
+	} `positional-args:"yes"`
+}
+
+// コマンドライン引数のバリデーションを行う
+func validateArgs(opts Options) error {
+	if !slices.Contains([]string{"plan", "apply"}, opts.Args.Action) {
+		return errors.New("plan か apply のいずれかを指定してください")
+	}
+
+	if opts.StatePath == "" {
+		return errors.New("コマンドライン引数にあるべき状態を記載したファイルのパスを指定してください")
+	}
+
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	return nil
+}
+
+// あるべき状態のファイルを読み込む
+// 拡張子が .yaml, .yml の場合は YAML、それ以外は JSON として読み込む
+// 誤記に気付けるように、知らない項目があればエラーにする
+func loadDesiredState(path string) (common.DesiredState, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return common.DesiredState{}, fmt.Errorf("ファイルの読み込みに失敗しました...%s", err.Error())
+	}
+
+	var state common.DesiredState
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&state)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&state)
+	}
+	if err != nil {
+		return common.DesiredState{}, fmt.Errorf("ファイルのパースに失敗しました...%s", err.Error())
+	}
+
+	err = common.ValidateDesiredState(state)
+	if err != nil {
+		return common.DesiredState{}, fmt.Errorf("ファイルの内容が不正です...%s", err.Error())
+	}
+	return state, nil
+}
+
+// 現在の状態を取得する
+// 別のモバイルゲートウェイに登録されている SIM を検出するため、すべてのゾーンのモバイルゲートウェイを取得する
+func getLiveState(opts Options, state common.DesiredState) (common.LiveState, error) {
+	live := common.LiveState{
+		AccountSims:  make(map[string]common.MgwSim),
+		MgwSims:      make(map[string][]common.MgwSim),
+		AttachedMgws: make(map[string]string),
+	}
+
+	accountSims, err := common.GetSimsInAccount(opts.AccessToken, opts.AccessTokenSecret)
+	if err != nil {
+		return common.LiveState{}, err
+	}
+	for _, sim := range accountSims {
+		live.AccountSims[sim.Status.ICCID] = sim.SimInfo()
+	}
+
+	// モバイルゲートウェイ配下の SIM(キーは "ゾーン/リソースID")
+	simsByMgw := make(map[string][]common.MgwSim)
+	for _, zone := range common.ValidZones {
+		mgws, err := common.GetMgwsInZone(opts.AccessToken, opts.AccessTokenSecret, zone)
+		if err != nil {
+			return common.LiveState{}, fmt.Errorf("%s: %s", zone, err.Error())
+		}
+		for _, mgw := range mgws {
+			sims, err := common.GetSimsInMGW(opts.AccessToken, opts.AccessTokenSecret, zone, mgw.ID)
+			if err != nil {
+				return common.LiveState{}, fmt.Errorf("%s: %s", zone, err.Error())
+			}
+			key := zone + "/" + mgw.ID
+			simsByMgw[key] = sims
+			for _, sim := range sims {
+				live.AttachedMgws[sim.ICCID] = key
+			}
+		}
+	}
+
+	for name, gateway := range state.Gateways {
+		sims, exists := simsByMgw[gateway.Zone+"/"+gateway.ResourceID]
+		if !exists {
+			return common.LiveState{}, fmt.Errorf("モバイルゲートウェイ(%s)が見つかりません", name)
+		}
+		live.MgwSims[name] = sims
+	}
+	return live, nil
+}
+
+// 登録が必要な SIM のパスコードが取得できるか確認する
+func checkPassCodes(ops []common.StateOperation) error {
+	for _, op := range ops {
+		if op.Kind != common.StateOpCreate {
+			continue
+		}
+		_, err := common.ResolvePassCode(op.Value)
+		if err != nil {
+			return fmt.Errorf("ICCID %s: %s", op.ICCID, err.Error())
+		}
+	}
+	return nil
+}
+
+func main() {
+	// コマンドライン引数の確認
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	err = validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// ファイルの読み込み
+	fmt.Printf("ファイル(%s)の読み込み中...", opts.StatePath)
+	state, err := loadDesiredState(opts.StatePath)
+	if err != nil {
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+
+	// 現在の状態との差分を計算
+	fmt.Printf("現在の状態の取得中...")
+	live, err := getLiveState(opts, state)
+	if err != nil {
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	ops, err := common.PlanDesiredState(state, live)
+	if err != nil {
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+
+	if len(ops) == 0 {
+		fmt.Println("あるべき状態との差分はありません")
+		os.Exit(0)
+	}
+	fmt.Printf("あるべき状態との差分: %d 件\n", len(ops))
+	for _, op := range ops {
+		fmt.Printf("  %s(ICCID: %s)\n", op.Description(), op.ICCID)
+	}
+
+	if opts.Args.Action == "plan" {
+		os.Exit(0)
+	}
+
+	// 適用を開始する前に、登録が必要な SIM のパスコードが取得できるか確認する
+	// plan では差分の確認のみなので、パスコードを参照しない
+	err = checkPassCodes(ops)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Println("あるべき状態の適用 開始")
+	err = common.ApplyStateOperations(opts.AccessToken, opts.AccessTokenSecret, state, ops)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("あるべき状態の適用 完了")
+
+	os.Exit(0)
+}
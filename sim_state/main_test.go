@@ -0,0 +1,256 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+func TestLoadDesiredState(t *testing.T) {
+	t.Run("YAMLとJSONで同じ内容になる", func(t *testing.T) {
+		fromYAML, err := loadDesiredState("testdata/state.yaml")
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		fromJSON, err := loadDesiredState("testdata/state.json")
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if !reflect.DeepEqual(fromYAML, fromJSON) {
+			t.Fatalf("states are different...%v, %v\n", fromYAML, fromJSON)
+		}
+		if len(fromYAML.Sims) != 2 || fromYAML.Sims[1].Activated != nil || *fromYAML.Sims[0].IMEILock != "350000000000000" {
+			t.Fatalf("unexpected state...%v", fromYAML)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("知らない項目があるとエラーになる", func(t *testing.T) {
+		_, err := loadDesiredState("testdata/unknown_field.yaml")
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestValidateDesiredState(t *testing.T) {
+	gateways := map[string]common.DesiredGateway{"site-a": {Zone: "is1b", ResourceID: "113000000000"}}
+
+	t.Run("未定義のモバイルゲートウェイを指定するとエラーになる", func(t *testing.T) {
+		state := common.DesiredState{Gateways: gateways, Sims: []common.DesiredSim{{ICCID: "8981040000000123400", Mgw: "site-b"}}}
+		err := common.ValidateDesiredState(state)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("同じモバイルゲートウェイでIPアドレスが重複するとエラーになる", func(t *testing.T) {
+		state := common.DesiredState{Gateways: gateways, Sims: []common.DesiredSim{
+			{ICCID: "8981040000000123400", Mgw: "site-a", IP: "192.168.1.1"},
+			{ICCID: "8981040000000123401", Mgw: "site-a", IP: "192.168.1.1"},
+		}}
+		err := common.ValidateDesiredState(state)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestPlanDesiredState(t *testing.T) {
+	state, err := loadDesiredState("testdata/state.yaml")
+	if err != nil {
+		t.Fatalf("nil error is expected, but got %s", err.Error())
+	}
+
+	t.Run("未登録のSIMは登録から、登録済みのSIMは差分のみ操作する", func(t *testing.T) {
+		live := common.LiveState{
+			AccountSims: map[string]common.MgwSim{
+				"8981040000000123401": {ICCID: "8981040000000123401", ResourceID: "113000000001", IP: "192.168.1.9"},
+			},
+			MgwSims: map[string][]common.MgwSim{
+				"site-a": {{ICCID: "8981040000000123401", ResourceID: "113000000001", IP: "192.168.1.9"}},
+			},
+			AttachedMgws: map[string]string{"8981040000000123401": "is1b/113000000000"},
+		}
+
+		ops, err := common.PlanDesiredState(state, live)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := []common.StateOperation{
+			{Kind: common.StateOpCreate, ICCID: "8981040000000123400", Value: "env:SIM_PASSCODE_123400"},
+			{Kind: common.StateOpAttach, ICCID: "8981040000000123400", Mgw: "site-a"},
+			{Kind: common.StateOpClearIP, ICCID: "8981040000000123401", ResourceID: "113000000001", Mgw: "site-a", Value: "192.168.1.9"},
+			{Kind: common.StateOpAssignIP, ICCID: "8981040000000123400", Mgw: "site-a", Value: "192.168.1.1"},
+			{Kind: common.StateOpAssignIP, ICCID: "8981040000000123401", ResourceID: "113000000001", Mgw: "site-a", Value: "192.168.1.2"},
+			{Kind: common.StateOpIMEILock, ICCID: "8981040000000123400", Value: "350000000000000"},
+			{Kind: common.StateOpActivate, ICCID: "8981040000000123400"},
+		}
+		if !reflect.DeepEqual(expected, ops) {
+			t.Fatalf("operations expected...%v, got ...%v\n", expected, ops)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("あるべき状態と同じなら操作は無い", func(t *testing.T) {
+		live := common.LiveState{
+			AccountSims: map[string]common.MgwSim{
+				"8981040000000123400": {ICCID: "8981040000000123400", ResourceID: "113000000000", IP: "192.168.1.1", IMEILock: true, Activated: true},
+				"8981040000000123401": {ICCID: "8981040000000123401", ResourceID: "113000000001", IP: "192.168.1.2"},
+			},
+			MgwSims: map[string][]common.MgwSim{
+				"site-a": {
+					{ICCID: "8981040000000123400", ResourceID: "113000000000", IP: "192.168.1.1"},
+					{ICCID: "8981040000000123401", ResourceID: "113000000001", IP: "192.168.1.2"},
+				},
+			},
+			AttachedMgws: map[string]string{
+				"8981040000000123400": "is1b/113000000000",
+				"8981040000000123401": "is1b/113000000000",
+			},
+		}
+
+		ops, err := common.PlanDesiredState(state, live)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if len(ops) != 0 {
+			t.Fatalf("unexpected operations...%v", ops)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("接続中のIMEIがIMEIロックするIMEIと異なる場合は設定し直す", func(t *testing.T) {
+		live := common.LiveState{
+			AccountSims: map[string]common.MgwSim{
+				"8981040000000123400": {ICCID: "8981040000000123400", ResourceID: "113000000000", IP: "192.168.1.1", IMEILock: true, Activated: true, ConnectedIMEI: "350000000000001"},
+				"8981040000000123401": {ICCID: "8981040000000123401", ResourceID: "113000000001", IP: "192.168.1.2"},
+			},
+			MgwSims: map[string][]common.MgwSim{
+				"site-a": {
+					{ICCID: "8981040000000123400", ResourceID: "113000000000", IP: "192.168.1.1"},
+					{ICCID: "8981040000000123401", ResourceID: "113000000001", IP: "192.168.1.2"},
+				},
+			},
+			AttachedMgws: map[string]string{
+				"8981040000000123400": "is1b/113000000000",
+				"8981040000000123401": "is1b/113000000000",
+			},
+		}
+
+		ops, err := common.PlanDesiredState(state, live)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := []common.StateOperation{
+			{Kind: common.StateOpIMEIUnlock, ICCID: "8981040000000123400", ResourceID: "113000000000"},
+			{Kind: common.StateOpIMEILock, ICCID: "8981040000000123400", ResourceID: "113000000000", Value: "350000000000000"},
+		}
+		if !reflect.DeepEqual(expected, ops) {
+			t.Fatalf("operations expected...%v, got ...%v\n", expected, ops)
+		}
+
+		// 接続中の IMEI が一致していれば操作は無い
+		sim := live.AccountSims["8981040000000123400"]
+		sim.ConnectedIMEI = "350000000000000"
+		live.AccountSims["8981040000000123400"] = sim
+		ops, err = common.PlanDesiredState(state, live)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if len(ops) != 0 {
+			t.Fatalf("unexpected operations...%v", ops)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("あるべき状態に含まれないSIMが使用中のIPアドレスを指定するとエラーになる", func(t *testing.T) {
+		live := common.LiveState{
+			AccountSims: map[string]common.MgwSim{
+				"8981040000000123401": {ICCID: "8981040000000123401", ResourceID: "113000000001"},
+			},
+			MgwSims: map[string][]common.MgwSim{
+				"site-a": {{ICCID: "8981040000000123499", ResourceID: "113000000099", IP: "192.168.1.2"}},
+			},
+			AttachedMgws: map[string]string{"8981040000000123499": "is1b/113000000000"},
+		}
+
+		_, err := common.PlanDesiredState(state, live)
+		if err != nil && strings.Contains(err.Error(), "8981040000000123499") {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+	t.Run("別のモバイルゲートウェイに登録されているSIMはIPアドレスが無くてもエラーになる", func(t *testing.T) {
+		live := common.LiveState{
+			AccountSims: map[string]common.MgwSim{
+				"8981040000000123401": {ICCID: "8981040000000123401", ResourceID: "113000000001"},
+			},
+			MgwSims:      map[string][]common.MgwSim{"site-a": {}},
+			AttachedMgws: map[string]string{"8981040000000123401": "tk1b/113000000099"},
+		}
+
+		_, err := common.PlanDesiredState(state, live)
+		if err != nil && strings.Contains(err.Error(), "move_sim") {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("IPアドレスを省略したSIMのIPアドレスが他のSIMに指定されていれば解除する", func(t *testing.T) {
+		unspecified := common.DesiredState{
+			Gateways: state.Gateways,
+			Sims: []common.DesiredSim{
+				{ICCID: "8981040000000123400", Mgw: "site-a", IP: "192.168.1.1"},
+				{ICCID: "8981040000000123401", Mgw: "site-a"},
+				{ICCID: "8981040000000123402", Mgw: "site-a"},
+			},
+		}
+		live := common.LiveState{
+			AccountSims: map[string]common.MgwSim{
+				"8981040000000123400": {ICCID: "8981040000000123400", ResourceID: "113000000000", IP: "192.168.1.9"},
+				"8981040000000123401": {ICCID: "8981040000000123401", ResourceID: "113000000001", IP: "192.168.1.1"},
+				"8981040000000123402": {ICCID: "8981040000000123402", ResourceID: "113000000002", IP: "192.168.1.2"},
+			},
+			MgwSims: map[string][]common.MgwSim{
+				"site-a": {
+					{ICCID: "8981040000000123400", ResourceID: "113000000000", IP: "192.168.1.9"},
+					{ICCID: "8981040000000123401", ResourceID: "113000000001", IP: "192.168.1.1"},
+					{ICCID: "8981040000000123402", ResourceID: "113000000002", IP: "192.168.1.2"},
+				},
+			},
+			AttachedMgws: map[string]string{
+				"8981040000000123400": "is1b/113000000000",
+				"8981040000000123401": "is1b/113000000000",
+				"8981040000000123402": "is1b/113000000000",
+			},
+		}
+
+		ops, err := common.PlanDesiredState(unspecified, live)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		// 他の SIM に指定されていない 192.168.1.2 はそのまま
+		expected := []common.StateOperation{
+			{Kind: common.StateOpClearIP, ICCID: "8981040000000123400", ResourceID: "113000000000", Mgw: "site-a", Value: "192.168.1.9"},
+			{Kind: common.StateOpClearIP, ICCID: "8981040000000123401", ResourceID: "113000000001", Mgw: "site-a", Value: "192.168.1.1"},
+			{Kind: common.StateOpAssignIP, ICCID: "8981040000000123400", ResourceID: "113000000000", Mgw: "site-a", Value: "192.168.1.1"},
+		}
+		if !reflect.DeepEqual(expected, ops) {
+			t.Fatalf("operations expected...%v, got ...%v\n", expected, ops)
+		} else {
+			t.Log("OK")
+		}
+	})
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	CsvPath           string   `long:"csv" description:"CSVファイルのパス(1列目のICCIDのみ参照)"`
+	ICCID             []string `long:"iccid" description:"対象のSIMのICCID(複数指定可)"`
+	AccessToken       string   `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string   `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Zone              string   `long:"zone" description:"さくらのクラウドゾーン(モバイルゲートウェイから対象を選ぶ場合)"`
+	MgwResourceID     string   `long:"mgw-resource-id" description:"モバイルゲートウェイのリソースID(モバイルゲートウェイから対象を選ぶ場合)"`
+	MgwName           string   `long:"mgw-name" description:"モバイルゲートウェイの名前(リソースIDの代わりに指定)"`
+	From              string   `long:"from" description:"集計開始日(YYYY-MM-DD)"`
+	To                string   `long:"to" description:"集計終了日(YYYY-MM-DD、この日を含む)"`
+	GroupBy           string   `long:"group-by" default:"day" description:"集計単位(day, sim)"`
+	Output            string   `long:"output" default:"csv" description:"出力形式(csv, json)"`
+}
+
+// 集計期間
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+// SIM ごとの通信量
+// 日ごとに集計した場合は Date に日付が入る
+type TrafficUsage struct {
+	ICCID         string `json:"iccid"`
+	ResourceID    string `json:"resource_id"`
+	Date          string `json:"date,omitempty"`
+	UplinkBytes   int64  `json:"uplink_bytes"`
+	DownlinkBytes int64  `json:"downlink_bytes"`
+	TotalBytes    int64  `json:"total_bytes"`
+}
+
+// 集計期間をパースする
+// 終了日はその日の終わりまでを含める
+func parsePeriod(from string, to string) (Period, error) {
+	if from == "" || to == "" {
+		return Period{}, errors.New("コマンドライン引数に集計開始日と集計終了日を指定してください")
+	}
+
+	start, err := time.ParseInLocation(time.DateOnly, from, common.TrafficLocation)
+	if err != nil {
+		return Period{}, fmt.Errorf("集計開始日は YYYY-MM-DD の形式で指定してください: %s", from)
+	}
+	end, err := time.ParseInLocation(time.DateOnly, to, common.TrafficLocation)
+	if err != nil {
+		return Period{}, fmt.Errorf("集計終了日は YYYY-MM-DD の形式で指定してください: %s", to)
+	}
+	if end.Before(start) {
+		return Period{}, errors.New("集計終了日は集計開始日以降の日付を指定してください")
+	}
+
+	return Period{Start: start, End: end.AddDate(0, 0, 1).Add(-time.Second)}, nil
+}
+
+// コマンドライン引数のバリデーションを行い、集計期間を返す
+func validateArgs(opts Options) (Period, error) {
+	fromList := opts.CsvPath != "" || len(opts.ICCID) > 0
+	fromMgw := opts.MgwResourceID != "" || opts.MgwName != ""
+	if fromList == fromMgw {
+		return Period{}, errors.New("コマンドライン引数にCSVファイルのパスかICCID、またはモバイルゲートウェイのいずれかを指定してください")
+	}
+	if opts.MgwResourceID != "" && opts.MgwName != "" {
+		return Period{}, errors.New("モバイルゲートウェイのリソースIDと名前は同時に指定できません")
+	}
+
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return Period{}, errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	if fromMgw {
+		err := common.ValidateZone(opts.Zone)
+		if err != nil {
+			return Period{}, err
+		}
+	}
+
+	if !slices.Contains([]string{"day", "sim"}, opts.GroupBy) {
+		return Period{}, errors.New("不正な集計単位です。day, sim から指定してください")
+	}
+
+	err := common.ValidateOutput(opts.Output, []string{"csv", "json"})
+	if err != nil {
+		return Period{}, err
+	}
+
+	return parsePeriod(opts.From, opts.To)
+}
+
+// 対象の SIM を取得する
+func loadTargetSims(opts Options) ([]common.MgwSim, error) {
+	if opts.MgwResourceID != "" || opts.MgwName != "" {
+		mgwID := opts.MgwResourceID
+		if opts.MgwName != "" {
+			var err error
+			mgwID, err = common.ResolveMgwID(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwName)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return common.GetSimsInMGW(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, mgwID)
+	}
+
+	// CSVファイル、コマンドライン引数の ICCID からアカウント内の SIM を探す
+	iccids, err := common.LoadICCIDList(opts.CsvPath, opts.ICCID)
+	if err != nil {
+		return nil, err
+	}
+	return common.FindSimsByICCID(opts.AccessToken, opts.AccessTokenSecret, iccids)
+}
+
+// SIM の日ごとの通信量を集計単位に合わせて TrafficUsage にする
+func summarizeTraffic(sim common.MgwSim, daily []common.DailyTraffic, groupBy string) []TrafficUsage {
+	if groupBy == "sim" {
+		usage := TrafficUsage{ICCID: sim.ICCID, ResourceID: sim.ResourceID}
+		for _, d := range daily {
+			usage.UplinkBytes += d.UplinkBytes
+			usage.DownlinkBytes += d.DownlinkBytes
+		}
+		usage.TotalBytes = usage.UplinkBytes + usage.DownlinkBytes
+		return []TrafficUsage{usage}
+	}
+
+	usages := make([]TrafficUsage, 0, len(daily))
+	for _, d := range daily {
+		usages = append(usages, TrafficUsage{
+			ICCID:         sim.ICCID,
+			ResourceID:    sim.ResourceID,
+			Date:          d.Date,
+			UplinkBytes:   d.UplinkBytes,
+			DownlinkBytes: d.DownlinkBytes,
+			TotalBytes:    d.UplinkBytes + d.DownlinkBytes,
+		})
+	}
+	return usages
+}
+
+// 通信量を指定された形式で出力する
+func writeUsages(w io.Writer, output string, groupBy string, usages []TrafficUsage) error {
+	if output == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(usages)
+	}
+
+	writer := csv.NewWriter(w)
+	header := []string{"iccid", "resource_id", "uplink_bytes", "downlink_bytes", "total_bytes"}
+	if groupBy == "day" {
+		header = slices.Insert(header, 2, "date")
+	}
+	err := writer.Write(header)
+	if err != nil {
+		return err
+	}
+	for _, usage := range usages {
+		record := []string{
+			usage.ICCID,
+			usage.ResourceID,
+			strconv.FormatInt(usage.UplinkBytes, 10),
+			strconv.FormatInt(usage.DownlinkBytes, 10),
+			strconv.FormatInt(usage.TotalBytes, 10),
+		}
+		if groupBy == "day" {
+			record = slices.Insert(record, 2, usage.Date)
+		}
+		err = writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func main() {
+	// コマンドラインオプションのパース
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// コマンドライン引数を バリデーションする
+	period, err := validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// 結果をパイプで渡せるように、標準エラー出力に出す
+	fmt.Fprintln(os.Stderr, "情報を取得しています...")
+
+	sims, err := loadTargetSims(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+
+	usages := make([]TrafficUsage, 0, len(sims))
+	for _, sim := range sims {
+		samples, err := common.GetSimTraffic(opts.AccessToken, opts.AccessTokenSecret, sim.ResourceID, period.Start, period.End)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ICCID: %s...%s\n", sim.ICCID, err.Error())
+			os.Exit(1)
+		}
+		usages = append(usages, summarizeTraffic(sim, common.AggregateTrafficByDay(samples), opts.GroupBy)...)
+	}
+
+	// 通信量を表示する
+	err = writeUsages(os.Stdout, opts.Output, opts.GroupBy, usages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "結果の出力に失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
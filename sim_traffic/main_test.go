@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// テストに使用する SIM
+var testSim = common.MgwSim{ICCID: "8981040000000123400", ResourceID: "113000000000"}
+
+// テストに使用する日ごとの通信量
+var testDaily = []common.DailyTraffic{
+	{Date: "2024-04-01", UplinkBytes: 1000, DownlinkBytes: 3000},
+	{Date: "2024-04-02", UplinkBytes: 500, DownlinkBytes: 0},
+}
+
+func TestAggregateTrafficByDay(t *testing.T) {
+	t.Run("計測値を次の計測時刻までの通信量として日本時間の日ごとに集計する", func(t *testing.T) {
+		// 日本時間の 2024-04-01 23:50 から5分ごとの計測値
+		base := time.Date(2024, 4, 1, 23, 50, 0, 0, common.TrafficLocation)
+		samples := []common.SimTrafficSample{
+			{Time: base.UTC(), UplinkBPS: 800, DownlinkBPS: 1600},
+			{Time: base.Add(5 * time.Minute).UTC(), UplinkBPS: 80, DownlinkBPS: 0},
+			{Time: base.Add(10 * time.Minute).UTC(), UplinkBPS: 8, DownlinkBPS: 8},
+		}
+
+		expected := []common.DailyTraffic{
+			{Date: "2024-04-01", UplinkBytes: 33000, DownlinkBytes: 60000},
+			{Date: "2024-04-02", UplinkBytes: 300, DownlinkBytes: 300},
+		}
+		actual := common.AggregateTrafficByDay(samples)
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("daily traffic expected...%v, got ...%v\n", expected, actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestParsePeriod(t *testing.T) {
+	t.Run("終了日はその日の終わりまでを含める", func(t *testing.T) {
+		period, err := parsePeriod("2024-04-01", "2024-04-30")
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expectedEnd := time.Date(2024, 4, 30, 23, 59, 59, 0, common.TrafficLocation)
+		if !period.End.Equal(expectedEnd) {
+			t.Fatalf("end expected...%v, got ...%v\n", expectedEnd, period.End)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("終了日が開始日より前だとエラーになる", func(t *testing.T) {
+		_, err := parsePeriod("2024-04-30", "2024-04-01")
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("日付の形式が不正だとエラーになる", func(t *testing.T) {
+		_, err := parsePeriod("2024/04/01", "2024-04-30")
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestSummarizeTraffic(t *testing.T) {
+	t.Run("SIMごとに集計すると期間の合計になる", func(t *testing.T) {
+		expected := []TrafficUsage{
+			{ICCID: "8981040000000123400", ResourceID: "113000000000", UplinkBytes: 1500, DownlinkBytes: 3000, TotalBytes: 4500},
+		}
+		actual := summarizeTraffic(testSim, testDaily, "sim")
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("usages expected...%v, got ...%v\n", expected, actual)
+		} else {
+			t.Log("OK")
+		}
+	})
+}
+
+func TestWriteUsages(t *testing.T) {
+	t.Run("日ごとの集計をCSV形式で出力する", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := writeUsages(&buf, "csv", "day", summarizeTraffic(testSim, testDaily, "day"))
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := "iccid,resource_id,date,uplink_bytes,downlink_bytes,total_bytes\n" +
+			"8981040000000123400,113000000000,2024-04-01,1000,3000,4000\n" +
+			"8981040000000123400,113000000000,2024-04-02,500,0,500\n"
+		if buf.String() != expected {
+			t.Fatalf("output expected...%s, got ...%s\n", expected, buf.String())
+		} else {
+			t.Log("OK")
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	ReportPath        string `long:"report" description:"register_sim の実行結果のレポートのJSONファイルのパス"`
+	AccessToken       string `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Yes               bool   `long:"yes" description:"確認せずに取り消す"`
+	DryRun            bool   `long:"dry-run" description:"APIを呼び出さずに取り消す内容のみ表示する"`
+}
+
+// コマンドライン引数のバリデーションを行う
+func validateArgs(opts Options) error {
+	if opts.ReportPath == "" {
+		return errors.New("コマンドライン引数にレポートのファイルのパスを指定してください")
+	}
+
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+	return nil
+}
+
+// 取り消す内容を表示する
+// 現在の状態から実際に行う処理だけを表示する
+func writeUndoPlan(w io.Writer, report common.RegisterReport, entries []common.UndoEntry) {
+	fmt.Fprintf(w, "以下のSIM %d 枚の登録を取り消します(%s に実行した登録)\n", len(entries), report.CreatedAt)
+	for _, entry := range entries {
+		fmt.Fprintf(w, "  ICCID: %s, リソースID: %s: %s\n", entry.Sim.ICCID, entry.Sim.ResourceID, strings.Join(entry.Steps(), ", "))
+	}
+}
+
+// confirm
+// 取り消しを実行してよいか確認する。yes と入力された場合のみ true を返す
+func confirm(r io.Reader, w io.Writer) bool {
+	fmt.Fprint(w, "取り消しを実行しますか? (yes/no): ")
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false
+	}
+	return strings.TrimSpace(answer) == "yes"
+}
+
+func main() {
+	// コマンドライン引数の確認
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	err = validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// レポートの読み込み
+	fmt.Printf("レポート(%s)の読み込み中...", opts.ReportPath)
+	report, err := common.LoadRegisterReport(opts.ReportPath)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+
+	if len(report.Sims) == 0 {
+		fmt.Println("取り消す登録はありません")
+		os.Exit(0)
+	}
+
+	// 現在の状態から取り消す内容を決める
+	fmt.Printf("現在の状態の取得中...")
+	live, err := common.GetUndoLiveState(opts.AccessToken, opts.AccessTokenSecret)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	entries, err := common.PlanUndoRegister(report.Sims, live)
+	if err != nil {
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+
+	// ドライランでなければ確認する
+	writeUndoPlan(os.Stdout, report, entries)
+	if !opts.DryRun && !opts.Yes && !confirm(os.Stdin, os.Stdout) {
+		fmt.Println("取り消しを中止しました")
+		os.Exit(1)
+	}
+
+	if opts.DryRun {
+		fmt.Println("SIM一括登録取り消し 開始(ドライラン)")
+	} else {
+		fmt.Println("SIM一括登録取り消し 開始")
+	}
+	err = common.UndoRegisterSimFromList(opts.AccessToken, opts.AccessTokenSecret, entries, opts.DryRun)
+	if err != nil {
+		// 取り消しに失敗
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("SIM一括登録取り消し 完了")
+
+	os.Exit(0)
+}
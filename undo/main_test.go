@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("レポートのパスが無いとエラーになる", func(t *testing.T) {
+		err := validateArgs(Options{AccessToken: "Token", AccessTokenSecret: "Secret"})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestLoadRegisterReport(t *testing.T) {
+	t.Run("レポートを読み込む", func(t *testing.T) {
+		report, err := common.LoadRegisterReport("testdata/report.json")
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		if len(report.Sims) != 2 || report.Sims[0].IP != "192.168.1.1" || !report.Sims[0].Activated || report.Sims[1].MgwResourceID != "" {
+			t.Fatalf("unexpected report...%v", report)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("リソースIDが無いSIMがあるとエラーになる", func(t *testing.T) {
+		_, err := common.LoadRegisterReport("testdata/missing_resource_id.json")
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestPlanUndoRegister(t *testing.T) {
+	report, err := common.LoadRegisterReport("testdata/report.json")
+	if err != nil {
+		t.Fatalf("nil error is expected, but got %s", err.Error())
+	}
+
+	t.Run("レポートのモバイルゲートウェイとIPアドレスのままのSIMを取り消す", func(t *testing.T) {
+		live := map[string]common.UndoLiveSim{
+			"290000000000": {ICCID: "8981040000000123400", Activated: true, AttachedMgw: "is1b/113000000000", IP: "192.168.1.1"},
+			"290000000001": {ICCID: "8981040000000123401"},
+		}
+		entries, err := common.PlanUndoRegister(report.Sims, live)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := []common.UndoEntry{
+			{Sim: report.Sims[0], ClearIP: true, Detach: true, Deactivate: true},
+			{Sim: report.Sims[1]},
+		}
+		if !reflect.DeepEqual(expected, entries) {
+			t.Fatalf("entries expected...%v, got ...%v\n", expected, entries)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("取り消し済みの処理は行わない", func(t *testing.T) {
+		live := map[string]common.UndoLiveSim{
+			"290000000000": {ICCID: "8981040000000123400", AttachedMgw: "is1b/113000000000"},
+		}
+		entries, err := common.PlanUndoRegister(report.Sims, live)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := []common.UndoEntry{
+			{Sim: report.Sims[0], Detach: true},
+			{Sim: report.Sims[1], Deleted: true},
+		}
+		if !reflect.DeepEqual(expected, entries) {
+			t.Fatalf("entries expected...%v, got ...%v\n", expected, entries)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("別のモバイルゲートウェイに移動したSIMやIPアドレスが変わったSIMはスキップする", func(t *testing.T) {
+		live := map[string]common.UndoLiveSim{
+			"290000000000": {ICCID: "8981040000000123400", Activated: true, AttachedMgw: "is1b/113000000000", IP: "192.168.1.9"},
+			"290000000001": {ICCID: "8981040000000123401", AttachedMgw: "tk1b/113000000001", IP: "192.168.1.2"},
+		}
+		entries, err := common.PlanUndoRegister(report.Sims, live)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		for _, entry := range entries {
+			if entry.SkipReason == "" || entry.ClearIP || entry.Detach || entry.Deactivate {
+				t.Fatalf("skip is expected...%v", entry)
+			}
+		}
+		t.Log("OK")
+	})
+
+	t.Run("ICCIDがレポートと一致しないとエラーになる", func(t *testing.T) {
+		live := map[string]common.UndoLiveSim{
+			"290000000000": {ICCID: "8981040000000123499"},
+		}
+		_, err := common.PlanUndoRegister(report.Sims, live)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestWriteUndoPlan(t *testing.T) {
+	t.Run("実際に行う処理だけを表示する", func(t *testing.T) {
+		report, err := common.LoadRegisterReport("testdata/report.json")
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		entries := []common.UndoEntry{
+			{Sim: report.Sims[0], Detach: true, Deactivate: true},
+			{Sim: report.Sims[1], SkipReason: "登録後に別のモバイルゲートウェイ(tk1b/113000000001)に登録されています"},
+		}
+		var buf bytes.Buffer
+		writeUndoPlan(&buf, report, entries)
+		expected := "以下のSIM 2 枚の登録を取り消します(2026-10-18T10:00:00+09:00 に実行した登録)\n" +
+			"  ICCID: 8981040000000123400, リソースID: 290000000000: モバイルゲートウェイ(113000000000)から削除, SIMを無効化, SIMを削除\n" +
+			"  ICCID: 8981040000000123401, リソースID: 290000000001: スキップ(登録後に別のモバイルゲートウェイ(tk1b/113000000001)に登録されています)\n"
+		if buf.String() != expected {
+			t.Fatalf("output expected...%s, got ...%s\n", expected, buf.String())
+		}
+		t.Log("OK")
+	})
+}
+
+func TestConfirm(t *testing.T) {
+	t.Run("yes と入力した場合のみ実行する", func(t *testing.T) {
+		var buf bytes.Buffer
+		if !confirm(strings.NewReader("yes\n"), &buf) {
+			t.Fatalf("confirmation is expected")
+		}
+		if confirm(strings.NewReader("y\n"), &buf) || confirm(strings.NewReader(""), &buf) {
+			t.Fatalf("cancel is expected")
+		}
+		t.Log("OK")
+	})
+}
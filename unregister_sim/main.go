@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	CsvPath           string   `long:"csv" description:"CSVファイルのパス(1列目のICCIDのみ参照)"`
+	ICCID             []string `long:"iccid" description:"登録解除するSIMのICCID(複数指定可)"`
+	AccessToken       string   `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string   `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Zone              string   `long:"zone" description:"さくらのクラウドゾーン"`
+	MgwResourceID     string   `long:"mgw-resource-id" description:"モバイルゲートウェイのリソースID"`
+	Deactivate        bool     `long:"deactivate" description:"登録解除後にSIMを無効化する"`
+	Delete            bool     `long:"delete" description:"登録解除後にSIMを削除する"`
+	DryRun            bool     `long:"dry-run" description:"APIを呼び出さずに実行内容のみ表示する"`
+}
+
+// コマンドライン引数のバリデーションを行う
+func validateArgs(opts Options) error {
+	if opts.CsvPath == "" && len(opts.ICCID) == 0 {
+		return errors.New("コマンドライン引数にCSVファイルのパスかICCIDを指定してください")
+	}
+
+	if opts.MgwResourceID == "" {
+		return errors.New("コマンドライン引数にモバイルゲートウェイのリソースIDを指定してください")
+	}
+
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	return common.ValidateZone(opts.Zone)
+}
+
+func main() {
+	// コマンドライン引数の確認
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	err = validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// 登録解除するSIMの読み込み
+	fmt.Printf("登録解除するSIMの読み込み中...")
+	iccids, err := common.LoadICCIDList(opts.CsvPath, opts.ICCID)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+
+	// SIMの登録を解除
+	if opts.DryRun {
+		fmt.Println("SIM一括登録解除 開始(ドライラン)")
+	} else {
+		fmt.Println("SIM一括登録解除 開始")
+	}
+	unregisterOpts := common.UnregisterOptions{Deactivate: opts.Deactivate, Delete: opts.Delete, DryRun: opts.DryRun}
+	err = common.UnregisterSimFromList(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwResourceID, iccids, unregisterOpts)
+	if err != nil {
+		// 登録解除に失敗
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Println("SIM一括登録解除 完了")
+
+	os.Exit(0)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+func TestLoadICCIDListCsv(t *testing.T) {
+	t.Run("register_simのCSVファイルからICCIDのみを読み込む", func(t *testing.T) {
+		csvPath := "testdata/load_test.csv"
+
+		iccids, err := common.LoadICCIDListCsv(csvPath)
+		if err != nil {
+			t.Fatalf("CSVファイルが読み込めません。%s", err.Error())
+		}
+
+		if len(iccids) != 10 || iccids[0] != "8981040000000123400" || iccids[9] != "8981040000000123409" {
+			t.Fatalf("unexpected iccids...%v", iccids)
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("存在しないCSVファイルはエラーになる", func(t *testing.T) {
+		_, err := common.LoadICCIDListCsv("testdata/not_found.csv")
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("CSVファイルのパスもICCIDも無いとエラーになる", func(t *testing.T) {
+		options := Options{AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", MgwResourceID: "aaaaaaa"}
+		err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("ICCIDのみの指定でもエラーにならない", func(t *testing.T) {
+		options := Options{ICCID: []string{"8981040000000123400"}, AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1a", MgwResourceID: "aaaaaaa"}
+		err := validateArgs(options)
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		} else {
+			t.Log("OK")
+		}
+	})
+
+	t.Run("不正なゾーンを入力したら、エラーが返る", func(t *testing.T) {
+		options := Options{CsvPath: "testdata.csv", AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "tk3a", MgwResourceID: "aaaaaaa"}
+		err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
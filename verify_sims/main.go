@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	CsvPath           string   `long:"csv" description:"CSVファイルのパス(1列目のICCIDのみ参照)"`
+	ICCID             []string `long:"iccid" description:"検証するSIMのICCID(複数指定可)"`
+	Mapping           string   `long:"mapping" description:"ICCIDと期待するIPアドレスのマッピングのCSVファイルのパス"`
+	AccessToken       string   `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string   `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	Zone              string   `long:"zone" description:"さくらのクラウドゾーン"`
+	MgwResourceID     string   `long:"mgw-resource-id" description:"モバイルゲートウェイのリソースID"`
+	MgwName           string   `long:"mgw-name" description:"モバイルゲートウェイの名前(リソースIDの代わりに指定)"`
+	CIDR              []string `long:"cidr" description:"SIMのIPアドレスが含まれるべきCIDR(複数指定可)"`
+}
+
+// コマンドライン引数のバリデーションを行い、IP アドレスの範囲の確認に使う CIDR を返す
+func validateArgs(opts Options) ([]*net.IPNet, error) {
+	fromList := opts.CsvPath != "" || len(opts.ICCID) > 0
+	fromMapping := opts.Mapping != ""
+	if fromList == fromMapping {
+		return nil, errors.New("コマンドライン引数にCSVファイルのパスかICCID、またはマッピングのCSVファイルのパスのいずれかを指定してください")
+	}
+
+	if (opts.MgwResourceID == "") == (opts.MgwName == "") {
+		return nil, errors.New("コマンドライン引数にモバイルゲートウェイのリソースIDか名前のいずれかを指定してください")
+	}
+
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return nil, errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	err := common.ValidateZone(opts.Zone)
+	if err != nil {
+		return nil, err
+	}
+
+	ipNets := make([]*net.IPNet, 0, len(opts.CIDR))
+	for _, cidr := range opts.CIDR {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("正しいフォーマットのCIDRを指定してください: %s", err.Error())
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, nil
+}
+
+// マッピングの CSV ファイルを読み込み、SIM ごとに期待する状態を返す
+// フォーマットは reip_sim のマッピングの CSV ファイルと同じ(iccid, IP アドレス)
+func loadMappingCsv(csvPath string) ([]common.SimExpectation, error) {
+	expectations := make([]common.SimExpectation, 0, 100)
+
+	// CSVファイルを開く
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("CSVファイルのオープンに失敗しました...%s", err.Error())
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// ファイルの末尾に到達
+				break
+			}
+			return nil, fmt.Errorf("読み込みに失敗しました...%s", err.Error())
+		}
+		if len(record) != 2 {
+			// フィールド数が一致しない
+			lineNo, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%d行目:列数が正しくありません...2列必要ですが%d列読み込みました", lineNo, len(record))
+		}
+		ip := strings.TrimSpace(record[1])
+		if net.ParseIP(ip).To4() == nil {
+			lineNo, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("%d行目:正しいフォーマットのIPアドレスを指定してください: %s", lineNo, ip)
+		}
+		expectations = append(expectations, common.SimExpectation{ICCID: strings.TrimSpace(record[0]), IP: ip})
+	}
+
+	return expectations, nil
+}
+
+// SIM ごとに期待する状態を作成する
+// マッピングを指定しなかった場合は、IP アドレスが設定されていれば良いものとする
+func buildExpectations(opts Options) ([]common.SimExpectation, error) {
+	if opts.Mapping != "" {
+		return loadMappingCsv(opts.Mapping)
+	}
+
+	iccids, err := common.LoadICCIDList(opts.CsvPath, opts.ICCID)
+	if err != nil {
+		return nil, err
+	}
+	expectations := make([]common.SimExpectation, 0, len(iccids))
+	for _, iccid := range iccids {
+		expectations = append(expectations, common.SimExpectation{ICCID: iccid})
+	}
+	return expectations, nil
+}
+
+func main() {
+	// コマンドライン引数の確認
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	ipNets, err := validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// CSVの読み込み
+	fmt.Printf("検証対象のSIMの読み込み中...")
+	expectations, err := buildExpectations(opts)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+
+	// 名前が指定されていればMGWのリソースIDを取得
+	if opts.MgwName != "" {
+		fmt.Printf("モバイルゲートウェイ(%s)の検索中...", opts.MgwName)
+		opts.MgwResourceID, err = common.ResolveMgwID(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwName)
+		if err != nil {
+			// エラーメッセージを出力
+			fmt.Println("[NG]")
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("[OK] リソースID: %s\n", opts.MgwResourceID)
+	}
+
+	// MGWのSIMの一覧を取得
+	fmt.Printf("モバイルゲートウェイのSIMの一覧の取得中...")
+	mgwSims, err := common.GetSimsInMGW(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwResourceID)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+
+	fmt.Println("登録結果の検証 開始")
+	results := common.VerifySims(mgwSims, expectations, ipNets)
+	mismatches := common.PrintVerifyResults(results)
+	fmt.Println("登録結果の検証 完了")
+	if mismatches > 0 {
+		fmt.Fprintf(os.Stderr, "登録結果が一致しないSIMが %d 枚あります\n", mismatches)
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("ICCIDとマッピングを同時に指定するとエラーになる", func(t *testing.T) {
+		options := Options{ICCID: []string{"8981040000000123400"}, Mapping: "testdata/mapping.csv", AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1b", MgwResourceID: "113000000000"}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("モバイルゲートウェイのリソースIDも名前も無いとエラーになる", func(t *testing.T) {
+		options := Options{ICCID: []string{"8981040000000123400"}, AccessToken: "Token", AccessTokenSecret: "Secret", Zone: "is1b"}
+		_, err := validateArgs(options)
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestLoadMappingCsv(t *testing.T) {
+	t.Run("ICCIDと期待するIPアドレスを読み込む", func(t *testing.T) {
+		expectations, err := loadMappingCsv("testdata/mapping.csv")
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		expected := []common.SimExpectation{
+			{ICCID: "8981040000000123400", IP: "192.168.1.1"},
+			{ICCID: "8981040000000123401", IP: "192.168.1.2"},
+		}
+		if !reflect.DeepEqual(expected, expectations) {
+			t.Fatalf("expectations expected...%v, got ...%v\n", expected, expectations)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("不正なIPアドレスがあるとエラーになる", func(t *testing.T) {
+		_, err := loadMappingCsv("testdata/invalid_ip.csv")
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestVerifySims(t *testing.T) {
+	sims := []common.MgwSim{
+		{ICCID: "8981040000000123400", IP: "192.168.1.1"},
+		{ICCID: "8981040000000123401", IP: "192.168.1.3"},
+		{ICCID: "8981040000000123402", IP: ""},
+		{ICCID: "8981040000000123403", IP: "10.0.0.1"},
+	}
+	expectations := []common.SimExpectation{
+		{ICCID: "8981040000000123400", IP: "192.168.1.1"},
+		{ICCID: "8981040000000123401", IP: "192.168.1.2"},
+		{ICCID: "8981040000000123402"},
+		{ICCID: "8981040000000123403"},
+		{ICCID: "8981040000000123404"},
+	}
+
+	t.Run("SIMごとに期待する状態と比較する", func(t *testing.T) {
+		_, ipNet, _ := net.ParseCIDR("192.168.1.0/24")
+		results := common.VerifySims(sims, expectations, []*net.IPNet{ipNet})
+
+		actual := make([]string, 0, len(results))
+		for _, result := range results {
+			actual = append(actual, result.Result)
+		}
+		expected := []string{
+			common.VerifyOK,
+			common.VerifyIPMismatch,
+			common.VerifyNoIP,
+			common.VerifyOutsideCIDR,
+			common.VerifyNotAttached,
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("results expected...%v, got ...%v\n", expected, actual)
+		}
+		t.Log("OK")
+	})
+
+	t.Run("CIDRを指定しなければIPアドレスが設定されていれば良い", func(t *testing.T) {
+		results := common.VerifySims(sims, expectations[3:4], nil)
+		if !results[0].OK() {
+			t.Fatalf("unexpected result...%v", results)
+		}
+		t.Log("OK")
+	})
+}
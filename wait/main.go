@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	flags "github.com/jessevdk/go-flags"
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+// コマンドライン引数
+type Options struct {
+	CsvPath           string   `long:"csv" description:"CSVファイルのパス(1列目のICCIDのみ参照)"`
+	ICCID             []string `long:"iccid" description:"対象のSIMのICCID(複数指定可)"`
+	AccessToken       string   `long:"token" description:"さくらのクラウドAPIアクセストークン"`
+	AccessTokenSecret string   `long:"secret" description:"さくらのクラウドAPIアクセスシークレット"`
+	State             string   `long:"state" description:"待機する状態(activated, attached, session_up)"`
+	Zone              string   `long:"zone" description:"さくらのクラウドゾーン(モバイルゲートウェイを指定する場合)"`
+	MgwResourceID     string   `long:"mgw-resource-id" description:"モバイルゲートウェイのリソースID(attached の場合は必須)"`
+	MgwName           string   `long:"mgw-name" description:"モバイルゲートウェイの名前(リソースIDの代わりに指定)"`
+	Interval          int      `long:"interval" default:"10" description:"状態を取得する間隔(秒)"`
+	Timeout           int      `long:"timeout" default:"600" description:"待機する最大の時間(秒)"`
+}
+
+// waitOptions
+// コマンドライン引数から待機のオプションを作成する
+func waitOptions(opts Options) common.WaitOptions {
+	return common.WaitOptions{
+		State:    opts.State,
+		Zone:     opts.Zone,
+		MgwID:    opts.MgwResourceID,
+		Interval: time.Duration(opts.Interval) * time.Second,
+		Timeout:  time.Duration(opts.Timeout) * time.Second,
+	}
+}
+
+// コマンドライン引数のバリデーションを行う
+func validateArgs(opts Options) error {
+	if opts.CsvPath == "" && len(opts.ICCID) == 0 {
+		return errors.New("コマンドライン引数にCSVファイルのパスかICCIDを指定してください")
+	}
+
+	if (opts.AccessToken == "") || (opts.AccessTokenSecret == "") {
+		return errors.New("コマンドライン引数にAPIアクセストークンとAPIアクセストークンシークレットを指定してください")
+	}
+
+	if opts.MgwResourceID != "" && opts.MgwName != "" {
+		return errors.New("モバイルゲートウェイのリソースIDと名前は同時に指定できません")
+	}
+	if opts.MgwResourceID != "" || opts.MgwName != "" {
+		err := common.ValidateZone(opts.Zone)
+		if err != nil {
+			return err
+		}
+	}
+
+	waitOpts := waitOptions(opts)
+	if opts.MgwName != "" {
+		// 名前はまだリソースIDに解決していないので、指定されたものとして扱う
+		waitOpts.MgwID = opts.MgwName
+	}
+	return common.ValidateWaitOptions(waitOpts)
+}
+
+func main() {
+	// コマンドライン引数の確認
+	var opts Options
+	parser := flags.NewParser(&opts, flags.Default)
+	_, err := parser.Parse()
+
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "コマンドライン引数のパースに失敗しました...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	err = validateArgs(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "コマンドライン引数が不正です...%s\n", err.Error())
+		os.Exit(1)
+	}
+
+	// 対象のSIMの読み込み
+	fmt.Printf("対象のSIMの読み込み中...")
+	iccids, err := common.LoadICCIDList(opts.CsvPath, opts.ICCID)
+	if err != nil {
+		// エラーメッセージを出力
+		fmt.Println("[NG]")
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("[OK]")
+
+	// 名前が指定されていればMGWのリソースIDを取得
+	if opts.MgwName != "" {
+		fmt.Printf("モバイルゲートウェイ(%s)の検索中...", opts.MgwName)
+		opts.MgwResourceID, err = common.ResolveMgwID(opts.AccessToken, opts.AccessTokenSecret, opts.Zone, opts.MgwName)
+		if err != nil {
+			// エラーメッセージを出力
+			fmt.Println("[NG]")
+			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("[OK] リソースID: %s\n", opts.MgwResourceID)
+	}
+
+	// Ctrl+C で待機を中断できるようにする
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("SIMの状態の待機 開始(%s, 最大%d秒)\n", opts.State, opts.Timeout)
+	err = common.WaitForSimState(ctx, opts.AccessToken, opts.AccessTokenSecret, iccids, waitOptions(opts))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		stop()
+		os.Exit(1)
+	}
+	fmt.Println("SIMの状態の待機 完了")
+
+	stop()
+	os.Exit(0)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sakura-internet/mobile-connect-commands/common"
+)
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("不正な状態を指定するとエラーになる", func(t *testing.T) {
+		err := validateArgs(Options{ICCID: []string{"8981040000000123400"}, AccessToken: "Token", AccessTokenSecret: "Secret", State: "online", Interval: 10, Timeout: 600})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("attached を待機する場合はモバイルゲートウェイが必要", func(t *testing.T) {
+		err := validateArgs(Options{ICCID: []string{"8981040000000123400"}, AccessToken: "Token", AccessTokenSecret: "Secret", State: "attached", Interval: 10, Timeout: 600})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+
+	t.Run("モバイルゲートウェイの名前を指定すれば attached を待機できる", func(t *testing.T) {
+		err := validateArgs(Options{ICCID: []string{"8981040000000123400"}, AccessToken: "Token", AccessTokenSecret: "Secret", State: "attached", Zone: "is1b", MgwName: "mgw01", Interval: 10, Timeout: 600})
+		if err != nil {
+			t.Fatalf("nil error is expected, but got %s", err.Error())
+		}
+		t.Log("OK")
+	})
+
+	t.Run("間隔に0を指定するとエラーになる", func(t *testing.T) {
+		err := validateArgs(Options{ICCID: []string{"8981040000000123400"}, AccessToken: "Token", AccessTokenSecret: "Secret", State: "activated", Interval: 0, Timeout: 600})
+		if err != nil {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}
+
+func TestSimInState(t *testing.T) {
+	t.Run("状態ごとに判定する", func(t *testing.T) {
+		up := common.MgwSim{ICCID: "8981040000000123400", IP: "192.168.1.1", Activated: true, SessionStatus: "UP"}
+		down := common.MgwSim{ICCID: "8981040000000123401", IP: "", Activated: false, SessionStatus: "DOWN"}
+		for _, state := range common.WaitStates {
+			if !common.SimInState(up, state) || common.SimInState(down, state) {
+				t.Fatalf("unexpected result...%s", state)
+			}
+		}
+		t.Log("OK")
+	})
+
+	t.Run("セッションの状態は大文字小文字を区別しない", func(t *testing.T) {
+		up := common.MgwSim{ICCID: "8981040000000123400", SessionStatus: "up"}
+		if common.SimInState(up, common.WaitSessionUp) {
+			t.Log("OK")
+		} else {
+			t.Fatalf("session_up is expected")
+		}
+	})
+}
+
+func TestWaitForSimState(t *testing.T) {
+	t.Run("中断済みの場合は状態を取得せずにエラーになる", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		opts := common.WaitOptions{State: common.WaitActivated, Interval: time.Second, Timeout: time.Minute}
+		err := common.WaitForSimState(ctx, "Token", "Secret", []string{"8981040000000123400"}, opts)
+		if err != nil && strings.Contains(err.Error(), "8981040000000123400") {
+			t.Log("OK")
+		} else {
+			t.Fatalf("error is expected")
+		}
+	})
+}